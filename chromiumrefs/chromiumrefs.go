@@ -0,0 +1,168 @@
+// Package chromiumrefs finds code in a Chromium checkout that mentions
+// csswg-drafts issues, e.g. TODOs and partial implementations.
+package chromiumrefs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Where Blink's code lives in the checkout.
+const DefaultSubdir = "third_party/blink"
+
+// Longest line text that is kept; the rest is cut.
+const maxTextLength = 160
+
+// Matches csswg-drafts issue references: urls, w3c/csswg-drafts#N and
+// "csswg-drafts issue N". grepPattern is the same for git grep.
+var (
+	issueRegexp = regexp.MustCompile(`(?i)csswg-drafts(?:/issues/|/pull/|#|\s+issue\s+#?)(\d+)`)
+	grepPattern = `csswg-drafts(/issues/|/pull/|#|[[:space:]]+issue[[:space:]]+#?)[0-9]+`
+)
+
+// A line of code that mentions a csswg-drafts issue.
+type Ref struct {
+	// Slash separated, relative to the checkout
+	Path string
+	Line int
+	// The line, trimmed
+	Text string
+}
+
+// Link to the line in Chromium code search.
+func (r *Ref) URL() string {
+	return SourceURL(r.Path, r.Line)
+}
+
+func SourceURL(path string, line int) string {
+	return fmt.Sprintf("https://source.chromium.org/chromium/chromium/src/+/main:%s;l=%d", path, line)
+}
+
+// Scans a Chromium checkout with git grep.
+type Scanner struct {
+	// Root of the checkout, i.e. the src directory
+	Dir string
+	// Part of the checkout to scan, DefaultSubdir if empty
+	Subdir string
+}
+
+func trimText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > maxTextLength {
+		text = text[:maxTextLength] + "..."
+	}
+	return text
+}
+
+// Returns the lines that mention any of the csswg-drafts issue numbers,
+// sorted by path and line.
+func (s *Scanner) Scan(numbers []int) (map[int][]*Ref, error) {
+	wanted := make(map[int]bool)
+	for _, number := range numbers {
+		wanted[number] = true
+	}
+	subdir := s.Subdir
+	if subdir == "" {
+		subdir = DefaultSubdir
+	}
+
+	output, err := exec.Command("git", "-C", s.Dir, "grep", "-n", "-I", "-i",
+		"--no-color", "-E", grepPattern, "--", subdir).Output()
+	var exit_err *exec.ExitError
+	// git grep exits with 1 when nothing matches.
+	if errors.As(err, &exit_err) && exit_err.ExitCode() == 1 {
+		return map[int][]*Ref{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("git grep: %v", err)
+	}
+
+	refs := make(map[int][]*Ref)
+	lines := bufio.NewScanner(bytes.NewReader(output))
+	lines.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lines.Scan() {
+		// path:line:text
+		parts := strings.SplitN(lines.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		line, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		seen := make(map[int]bool)
+		for _, match := range issueRegexp.FindAllStringSubmatch(parts[2], -1) {
+			number, err := strconv.Atoi(match[1])
+			if err != nil || !wanted[number] || seen[number] {
+				continue
+			}
+			seen[number] = true
+			refs[number] = append(refs[number],
+				&Ref{Path: parts[0], Line: line, Text: trimText(parts[2])})
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("reading git grep output: %v", err)
+	}
+
+	for _, number_refs := range refs {
+		sort.Slice(number_refs, func(i, j int) bool {
+			if number_refs[i].Path != number_refs[j].Path {
+				return number_refs[i].Path < number_refs[j].Path
+			}
+			return number_refs[i].Line < number_refs[j].Line
+		})
+	}
+	return refs, nil
+}
+
+// Returns whether a and b mention the issue at the same places. Line numbers
+// are ignored, since unrelated edits move them all the time.
+func SameRefs(a, b []*Ref) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Path != b[i].Path || a[i].Text != b[i].Text {
+			return false
+		}
+	}
+	return true
+}
+
+// Most locations listed in comments; code search has the rest.
+const maxListedRefs = 30
+
+// Returns a markdown list of the locations.
+func Markdown(refs []*Ref) string {
+	var b strings.Builder
+	for i, ref := range refs {
+		if i == maxListedRefs {
+			fmt.Fprintf(&b, "* ...and %d more\n", len(refs)-i)
+			break
+		}
+		fmt.Fprintf(&b, "* [%s:%d](%s): `%s`\n", ref.Path, ref.Line, ref.URL(),
+			strings.ReplaceAll(ref.Text, "`", "'"))
+	}
+	return b.String()
+}
+
+// Returns a plain text list of the locations, e.g. for crbug comments.
+func Text(refs []*Ref) string {
+	var b strings.Builder
+	for i, ref := range refs {
+		if i == maxListedRefs {
+			fmt.Fprintf(&b, "  ...and %d more\n", len(refs)-i)
+			break
+		}
+		fmt.Fprintf(&b, "  %s\n    %s\n", ref.URL(), ref.Text)
+	}
+	return b.String()
+}
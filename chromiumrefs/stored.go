@@ -0,0 +1,23 @@
+package chromiumrefs
+
+import (
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+)
+
+// Converts refs to how fsresolutions stores them.
+func ToStored(refs []*Ref) []*fsresolutions.ChromiumRef {
+	var stored []*fsresolutions.ChromiumRef
+	for _, ref := range refs {
+		stored = append(stored, &fsresolutions.ChromiumRef{Path: ref.Path, Line: ref.Line, Text: ref.Text})
+	}
+	return stored
+}
+
+// Converts refs stored by fsresolutions back.
+func FromStored(stored []*fsresolutions.ChromiumRef) []*Ref {
+	var refs []*Ref
+	for _, ref := range stored {
+		refs = append(refs, &Ref{Path: ref.Path, Line: ref.Line, Text: ref.Text})
+	}
+	return refs
+}
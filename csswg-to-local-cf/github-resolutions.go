@@ -2,6 +2,7 @@
 package p
 
 import (
+  _ "embed"
   "golang.org/x/oauth2"
   "github.com/google/go-github/github"
   "fmt"
@@ -11,9 +12,11 @@ import (
   "regexp"
   "strings"
   "strconv"
+  "os"
   gcpsm "cloud.google.com/go/secretmanager/apiv1"
   gcpsmpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
   "github.com/chromium-helper/csswg-resolutions/fsresolutions"
+  "github.com/chromium-helper/csswg-resolutions/suggestions"
 )
 
 const (
@@ -26,13 +29,48 @@ const (
 
   resOwner = "chromium-helper"
   resRepo = "csswg-resolutions"
+
+  // Directive that triagers use to accept a suggested component. Keep this in
+  // sync with the task handler.
+  acceptDirective = "accept"
+  acceptOwnersDirective = "accept-owners"
+
+  // Maximum number of suggested ccs
+  maxSuggestedCcs = 3
+
+  // Declarative auto-triage rules, embedded below.
+  triageRulesFile = "triage-rules.yaml"
 )
 
+// The files are embedded, since the function may not run in the source
+// directory.
+var (
+  // Maps spec names to components
+  //go:embed component-table.json
+  componentTableContents []byte
+
+  //go:embed triage-rules.yaml
+  triageRulesContents []byte
+)
+
+// Label prefix that causes the task handler to file a crbug, read from the same
+// COMPONENT_LABEL_PREFIX as the task handler.
+var componentLabelPrefix = labelPrefixFromEnv("COMPONENT_LABEL_PREFIX", "crbug:")
+
+func labelPrefixFromEnv(name string, fallback string) string {
+  if prefix := os.Getenv(name); prefix != "" {
+    return prefix
+  }
+  return fallback
+}
+
 type App struct {
   gh_client_ro *github.Client
   gh_client_rw *github.Client
   StartTime time.Time
   FSClient *fsresolutions.Client
+  componentTable suggestions.ComponentTable
+  triageRules []*TriageRule
 }
 
 type CSSWGResolution struct {
@@ -41,6 +79,16 @@ type CSSWGResolution struct {
 
   Resolutions []string
   CommentURL string
+  Time time.Time
+}
+
+func (r *CSSWGResolution) Recorded() *fsresolutions.RecordedResolution {
+  return &fsresolutions.RecordedResolution{
+    CommentId: r.CommentID,
+    CommentURL: r.CommentURL,
+    Text: r.Resolutions,
+    Time: r.Time,
+  }
 }
 
 // Creates a new app to use
@@ -162,13 +210,14 @@ func parseResolutions(comments []*github.IssueComment) (
     url_parts := strings.Split(*comment.IssueURL, "/")
     issue_number, err := strconv.Atoi(url_parts[len(url_parts)-1])
     if err != nil {
-      return nil, fmt.Errorf("atoi for url %s: %v\n", comment.IssueURL, err)
+      return nil, fmt.Errorf("atoi for url %s: %v\n", comment.GetIssueURL(), err)
     }
 
     resolution := &CSSWGResolution{
       CommentID: *comment.ID,
       IssueNumber: issue_number,
       CommentURL: *comment.HTMLURL,
+      Time: comment.GetCreatedAt(),
     }
     resolution.Resolutions = append(resolution.Resolutions, matches...)
     results = append(results, resolution)
@@ -249,6 +298,22 @@ func (app *App) createNewIssue(
     }
   }
 
+  // Suggest everything before the issue exists, so that a failure can't leave
+  // a tracking issue without its data.
+  component_suggestions, err := app.suggestComponents(labels)
+  if err != nil {
+    return fmt.Errorf("suggestComponents: %v", err)
+  }
+  owner, ccs, err := app.suggestPeople(labels)
+  if err != nil {
+    return fmt.Errorf("suggestPeople: %v", err)
+  }
+  rule, err := app.matchTriageRule(title, labels, resolution)
+  if err != nil {
+    return fmt.Errorf("app.matchTriageRule: %v", err)
+  }
+  body += createPeopleSuggestionText(owner, ccs)
+
   request := &github.IssueRequest{
     Title: &title,
     Body: &body,
@@ -268,10 +333,136 @@ func (app *App) createNewIssue(
     CsswgDraftsId: csswgissue.GetNumber(),
     CsswgResolutionsId: resissue.GetNumber(),
     ResolutionCommentIds: []int64{resolution.CommentID},
+    Title: title,
+    SpecLabels: labels,
+    Resolutions: []*fsresolutions.RecordedResolution{ resolution.Recorded() },
+  }
+  for _, suggestion := range component_suggestions {
+    fsdata.SuggestedComponents =
+      append(fsdata.SuggestedComponents, suggestion.Component)
+  }
+  if owner != nil {
+    fsdata.SuggestedOwner = owner.Email
+  }
+  for _, cc := range ccs {
+    fsdata.SuggestedCcList = append(fsdata.SuggestedCcList, cc.Email)
+  }
+  if rule != nil && rule.Close {
+    fsdata.TriageOutcome = fsresolutions.TriageOutcomeNoAction
+    fsdata.TriageDecidedTime = time.Now()
+    fsdata.TriageReason = triageRuleReason(rule)
   }
   if err = app.FSClient.SetData( docname, fsdata); err != nil {
     return fmt.Errorf("SetData: %v", err)
   }
+
+  if rule != nil {
+    if err = app.applyTriageRule(resissue.GetNumber(), rule); err != nil {
+      return fmt.Errorf("app.applyTriageRule: %v", err)
+    }
+  }
+
+  // Suggestions aren't useful if a rule already made the decision.
+  triaged := rule != nil && (rule.Close || rule.Component != "")
+  if len(component_suggestions) != 0 && !triaged {
+    err = app.addSuggestionComment(resissue.GetNumber(), component_suggestions)
+    if err != nil {
+      return fmt.Errorf("app.addSuggestionComment: %v", err)
+    }
+  }
+  return nil
+}
+
+// Suggests components based on the component table and on what triagers
+// picked for the same specs in the past.
+func (app *App) suggestComponents(labels []string) (
+    []suggestions.ComponentSuggestion, error) {
+  if app.componentTable == nil {
+    table, err := suggestions.ParseComponentTable(componentTableContents)
+    if err != nil {
+      return nil, fmt.Errorf("ParseComponentTable: %v", err)
+    }
+    app.componentTable = table
+  }
+
+  stats, err := app.FSClient.LoadComponentStats()
+  if err != nil {
+    return nil, fmt.Errorf("LoadComponentStats: %v", err)
+  }
+  return suggestions.SuggestComponents(labels, app.componentTable, stats), nil
+}
+
+// Suggests an owner and ccs based on who triagers picked for the same specs in
+// the past. The owner is nil if there is no suggestion.
+func (app *App) suggestPeople(labels []string) (
+    *suggestions.PersonSuggestion, []suggestions.PersonSuggestion, error) {
+  owner_stats, err := app.FSClient.LoadOwnerStats()
+  if err != nil {
+    return nil, nil, fmt.Errorf("LoadOwnerStats: %v", err)
+  }
+  cc_stats, err := app.FSClient.LoadCcStats()
+  if err != nil {
+    return nil, nil, fmt.Errorf("LoadCcStats: %v", err)
+  }
+
+  var owner *suggestions.PersonSuggestion
+  var exclude []string
+  if owners := suggestions.SuggestPeople(labels, owner_stats, 1);
+     len(owners) != 0 {
+    owner = &owners[0]
+    exclude = append(exclude, owner.Email)
+  }
+  ccs := suggestions.SuggestPeople(labels, cc_stats, maxSuggestedCcs, exclude...)
+  return owner, ccs, nil
+}
+
+func createPeopleSuggestionText(
+    owner *suggestions.PersonSuggestion,
+    ccs []suggestions.PersonSuggestion) string {
+  if owner == nil && len(ccs) == 0 {
+    return ""
+  }
+
+  body := "\n\nBased on past crbugs for these specs:\n"
+  if owner != nil {
+    body += fmt.Sprintf("* Suggested owner: %s (owned %d)\n",
+      owner.Email, owner.Count)
+  }
+  if len(ccs) != 0 {
+    var parts []string
+    for _, cc := range ccs {
+      parts = append(parts, fmt.Sprintf("%s (cc'd %d)", cc.Email, cc.Count))
+    }
+    body += fmt.Sprintf("* Suggested cc: %s\n", strings.Join(parts, ", "))
+  }
+  body += fmt.Sprintf("\nTo use these when filing a crbug, reply with `%s`.",
+    acceptOwnersDirective)
+  return body
+}
+
+func createSuggestionText(
+    component_suggestions []suggestions.ComponentSuggestion) string {
+  body := "Suggested component(s), based on the spec labels:\n\n"
+  for _, suggestion := range component_suggestions {
+    body += fmt.Sprintf("* `%s` (%s)\n", suggestion.Component, suggestion.Reason)
+  }
+  body += fmt.Sprintf("\nTo file a crbug in `%s`, reply with `%s`. " +
+    "Otherwise, triage as usual.", component_suggestions[0].Component,
+    acceptDirective)
+  return body
+}
+
+func (app *App) addSuggestionComment(
+    number int,
+    component_suggestions []suggestions.ComponentSuggestion) error {
+  body := createSuggestionText(component_suggestions)
+  comment := &github.IssueComment{ Body: &body }
+  _, _, err := app.github_client().Issues.CreateComment(
+      context.Background(), resOwner, resRepo, number, comment)
+  if err != nil {
+    return fmt.Errorf("github.CreateComment: %v\n", err)
+  }
+  log.Printf("Added suggestion comment to issue #%d\n", number)
   return nil
 }
 
@@ -294,9 +485,9 @@ func (app *App) addResolutionComment(
 
   data.ResolutionCommentIds =
     append(data.ResolutionCommentIds, resolution.CommentID)
-  if err = app.FSClient.UpdateDataSetResolutionCommentIds(docname, data);
-     err != nil {
-    return fmt.Errorf("UpdateDataSetResolutionCommentIds: %v", err)
+  data.Resolutions = append(data.Resolutions, resolution.Recorded())
+  if err = app.FSClient.UpdateDataSetResolutions(docname, data); err != nil {
+    return fmt.Errorf("UpdateDataSetResolutions: %v", err)
   }
   return nil
 }
@@ -2,11 +2,17 @@ module github-resolutions
 
 go 1.19
 
+replace github.com/chromium-helper/csswg-resolutions/fsresolutions => ../fsresolutions
+
+replace github.com/chromium-helper/csswg-resolutions/suggestions => ../suggestions
+
 require (
 	cloud.google.com/go/secretmanager v1.10.0
 	github.com/chromium-helper/csswg-resolutions/fsresolutions v0.1.0
+	github.com/chromium-helper/csswg-resolutions/suggestions v0.0.0-00010101000000-000000000000
 	github.com/google/go-github v17.0.0+incompatible
 	golang.org/x/oauth2 v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
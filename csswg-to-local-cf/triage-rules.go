@@ -0,0 +1,215 @@
+package p
+
+import (
+  "context"
+  "fmt"
+  "log"
+  "os"
+  "regexp"
+  "strings"
+
+  "github.com/chromium-helper/csswg-resolutions/suggestions"
+  "github.com/google/go-github/github"
+  "gopkg.in/yaml.v3"
+)
+
+// A rule matches if all of its non-empty conditions match. For example:
+//
+//   - name: css-print is not implemented in Chromium
+//     specs: [css-print, css-speech]
+//     close: true
+//     reason: Chromium does not implement these specs.
+//
+//   - name: grid resolutions
+//     specs: [css-grid]
+//     resolution: "(?i)masonry"
+//     component: Blink>Layout>Grid
+//     assign: [some-triager]
+type TriageRule struct {
+  Name string `yaml:"name"`
+
+  // Conditions
+  // Matches if any of the issue's specs (labels without level) is listed
+  Specs []string `yaml:"specs"`
+  // Regexps for the csswg-drafts issue title, and for any of the resolutions
+  Title string `yaml:"title"`
+  Resolution string `yaml:"resolution"`
+
+  // Actions
+  Close bool `yaml:"close"`
+  // Adds a crbug:<component> label, which causes the crbug to be filed
+  Component string `yaml:"component"`
+  // GitHub logins to assign the tracking issue to
+  Assign []string `yaml:"assign"`
+  // Explanation included in the bot comment
+  Reason string `yaml:"reason"`
+
+  titleRegexp *regexp.Regexp
+  resolutionRegexp *regexp.Regexp
+}
+
+type triageRulesYaml struct {
+  Rules []*TriageRule `yaml:"rules"`
+}
+
+// Loads the rules, and compiles their regexps.
+func LoadTriageRules(path string) ([]*TriageRule, error) {
+  contents, err := os.ReadFile(path)
+  if err != nil {
+    return nil, fmt.Errorf("ReadFile: %v", err)
+  }
+  return ParseTriageRules(contents)
+}
+
+// Parses the yaml contents of a rules file, see LoadTriageRules.
+func ParseTriageRules(contents []byte) ([]*TriageRule, error) {
+  var file triageRulesYaml
+  err := yaml.Unmarshal(contents, &file)
+  if err != nil {
+    return nil, fmt.Errorf("yaml.Unmarshal: %v", err)
+  }
+
+  for _, rule := range file.Rules {
+    if rule.Name == "" {
+      return nil, fmt.Errorf("rule without a name")
+    }
+    if !rule.Close && rule.Component == "" && len(rule.Assign) == 0 {
+      return nil, fmt.Errorf("rule %q has no actions", rule.Name)
+    }
+    if rule.Title != "" {
+      if rule.titleRegexp, err = regexp.Compile(rule.Title); err != nil {
+        return nil, fmt.Errorf("rule %q title: %v", rule.Name, err)
+      }
+    }
+    if rule.Resolution != "" {
+      rule.resolutionRegexp, err = regexp.Compile(rule.Resolution)
+      if err != nil {
+        return nil, fmt.Errorf("rule %q resolution: %v", rule.Name, err)
+      }
+    }
+  }
+  return file.Rules, nil
+}
+
+func (rule *TriageRule) Matches(
+    labels []string, title string, resolutions []string) bool {
+  if len(rule.Specs) != 0 {
+    matched := false
+    for _, spec := range suggestions.SpecNames(labels) {
+      for _, rule_spec := range rule.Specs {
+        matched = matched || spec == rule_spec
+      }
+    }
+    if !matched {
+      return false
+    }
+  }
+
+  if rule.titleRegexp != nil && !rule.titleRegexp.MatchString(title) {
+    return false
+  }
+
+  if rule.resolutionRegexp != nil {
+    matched := false
+    for _, resolution := range resolutions {
+      matched = matched || rule.resolutionRegexp.MatchString(resolution)
+    }
+    if !matched {
+      return false
+    }
+  }
+  return true
+}
+
+// Returns the first matching rule, or nil.
+func findTriageRule(rules []*TriageRule,
+    labels []string, title string, resolutions []string) *TriageRule {
+  for _, rule := range rules {
+    if rule.Matches(labels, title, resolutions) {
+      return rule
+    }
+  }
+  return nil
+}
+
+func createTriageRuleText(rule *TriageRule) string {
+  body := fmt.Sprintf("This issue was triaged automatically by the " +
+    "rule \"%s\":\n\n", rule.Name)
+  if rule.Component != "" {
+    body += fmt.Sprintf("* Added the `%s%s` label, so a crbug will be filed\n",
+      componentLabelPrefix, rule.Component)
+  }
+  if len(rule.Assign) != 0 {
+    body += fmt.Sprintf("* Assigned to @%s\n", strings.Join(rule.Assign, ", @"))
+  }
+  if rule.Close {
+    body += "* Closed, since no further work is expected\n"
+  }
+  if rule.Reason != "" {
+    body += fmt.Sprintf("\n%s\n", rule.Reason)
+  }
+  body += fmt.Sprintf("\nIf this is wrong, please reopen the issue or " +
+    "update `%s` in the bot's source.", triageRulesFile)
+  return body
+}
+
+// Returns the first triage rule that matches a new tracking issue, if any.
+func (app *App) matchTriageRule(title string, labels []string,
+    resolution *CSSWGResolution) (*TriageRule, error) {
+  if app.triageRules == nil {
+    rules, err := ParseTriageRules(triageRulesContents)
+    if err != nil {
+      return nil, fmt.Errorf("ParseTriageRules: %v", err)
+    }
+    app.triageRules = rules
+  }
+  return findTriageRule(app.triageRules, labels, title, resolution.Resolutions), nil
+}
+
+// Reason recorded for issues that a rule closes. The task handler skips issues
+// the bot closed, so the outcome is recorded when the issue is created.
+func triageRuleReason(rule *TriageRule) string {
+  return fmt.Sprintf("Triage rule \"%s\"", rule.Name)
+}
+
+// Applies a triage rule to a newly created tracking issue.
+func (app *App) applyTriageRule(number int, rule *TriageRule) error {
+  // Comment first, so the explanation is there before any webhook events
+  // from the actions below are processed.
+  body := createTriageRuleText(rule)
+  comment := &github.IssueComment{ Body: &body }
+  _, _, err := app.github_client().Issues.CreateComment(
+      context.Background(), resOwner, resRepo, number, comment)
+  if err != nil {
+    return fmt.Errorf("github.CreateComment: %v", err)
+  }
+
+  if rule.Component != "" {
+    _, _, err = app.github_client().Issues.AddLabelsToIssue(
+        context.Background(), resOwner, resRepo, number,
+        []string{componentLabelPrefix + rule.Component})
+    if err != nil {
+      return fmt.Errorf("github.AddLabelsToIssue: %v", err)
+    }
+  }
+
+  if len(rule.Assign) != 0 {
+    _, _, err = app.github_client().Issues.AddAssignees(
+        context.Background(), resOwner, resRepo, number, rule.Assign)
+    if err != nil {
+      return fmt.Errorf("github.AddAssignees: %v", err)
+    }
+  }
+
+  if rule.Close {
+    state := "closed"
+    _, _, err = app.github_client().Issues.Edit(
+        context.Background(), resOwner, resRepo, number,
+        &github.IssueRequest{ State: &state })
+    if err != nil {
+      return fmt.Errorf("github.Edit: %v", err)
+    }
+  }
+  log.Printf("Applied triage rule %q to issue #%d\n", rule.Name, number)
+  return nil
+}
@@ -0,0 +1,155 @@
+package p
+
+import (
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "os"
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+
+  "github.com/chromium-helper/csswg-resolutions/suggestions"
+  "github.com/google/go-github/github"
+)
+
+func TestLoadTriageRules(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "rules.yaml")
+  contents := `
+rules:
+  - name: print
+    specs: [css-print]
+    close: true
+  - name: masonry
+    title: "(?i)masonry"
+    component: Blink>Layout>Grid
+`
+  if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+    t.Fatalf("WriteFile: %v", err)
+  }
+  rules, err := LoadTriageRules(path)
+  if err != nil {
+    t.Fatalf("LoadTriageRules: %v", err)
+  }
+  if len(rules) != 2 || rules[0].Name != "print" || rules[1].titleRegexp == nil {
+    t.Errorf("LoadTriageRules returned %+v", rules)
+  }
+
+  if _, err := LoadTriageRules(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+    t.Errorf("LoadTriageRules of a missing file succeeded")
+  }
+}
+
+func TestParseTriageRulesErrors(t *testing.T) {
+  tests := []struct {
+    name string
+    contents string
+  }{
+    {"no name", "rules:\n  - close: true\n"},
+    {"no actions", "rules:\n  - name: nothing\n    specs: [css-grid]\n"},
+    {"bad title", "rules:\n  - name: bad\n    title: \"(\"\n    close: true\n"},
+    {"bad resolution", "rules:\n  - name: bad\n    resolution: \"[\"\n    close: true\n"},
+    {"not yaml", "rules: ["},
+  }
+  for _, test := range tests {
+    if rules, err := ParseTriageRules([]byte(test.contents)); err == nil {
+      t.Errorf("%s: ParseTriageRules returned %+v", test.name, rules)
+    }
+  }
+}
+
+// The embedded files must parse, or the poller fails at its first new issue.
+func TestEmbeddedFiles(t *testing.T) {
+  if _, err := ParseTriageRules(triageRulesContents); err != nil {
+    t.Errorf("ParseTriageRules(%s): %v", triageRulesFile, err)
+  }
+  if _, err := suggestions.ParseComponentTable(componentTableContents); err != nil {
+    t.Errorf("ParseComponentTable: %v", err)
+  }
+}
+
+func TestTriageRuleMatches(t *testing.T) {
+  rules, err := ParseTriageRules([]byte(`
+rules:
+  - name: print
+    specs: [css-print, css-speech]
+    close: true
+  - name: grid masonry
+    specs: [css-grid]
+    resolution: "(?i)masonry"
+    component: Blink>Layout>Grid
+  - name: anchor title
+    title: "^\\[css-anchor-position"
+    assign: [anchor-triager]
+`))
+  if err != nil {
+    t.Fatalf("ParseTriageRules: %v", err)
+  }
+
+  tests := []struct {
+    name string
+    labels []string
+    title string
+    resolutions []string
+    want string
+  }{
+    {"spec with level", []string{"css-print-1"}, "", nil, "print"},
+    {"second listed spec", []string{"css-fonts-4", "css-speech-1"}, "", nil, "print"},
+    {"spec and resolution", []string{"css-grid-3"}, "", []string{"RESOLVED: Adopt Masonry"}, "grid masonry"},
+    {"spec without resolution", []string{"css-grid-3"}, "", []string{"RESOLVED: no change"}, ""},
+    {"resolution without spec", []string{"css-flexbox-1"}, "", []string{"RESOLVED: masonry"}, ""},
+    {"title", nil, "[css-anchor-position-1] Fallback", nil, "anchor title"},
+    {"title not at start", nil, "Re: [css-anchor-position-1]", nil, ""},
+    {"first match wins", []string{"css-print"}, "[css-anchor-position-1]", nil, "print"},
+    {"no labels", nil, "", nil, ""},
+  }
+  for _, test := range tests {
+    rule := findTriageRule(rules, test.labels, test.title, test.resolutions)
+    got := ""
+    if rule != nil {
+      got = rule.Name
+    }
+    if got != test.want {
+      t.Errorf("%s: matched %q, want %q", test.name, got, test.want)
+    }
+  }
+}
+
+func TestApplyTriageRule(t *testing.T) {
+  var requests []string
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    requests = append(requests, r.Method + " " + r.URL.Path)
+    w.Header().Set("Content-Type", "application/json")
+    if strings.HasSuffix(r.URL.Path, "/labels") {
+      w.Write([]byte("[]"))
+      return
+    }
+    w.Write([]byte("{}"))
+  }))
+  defer server.Close()
+  client := github.NewClient(nil)
+  base_url, err := url.Parse(server.URL + "/")
+  if err != nil {
+    t.Fatalf("url.Parse: %v", err)
+  }
+  client.BaseURL = base_url
+
+  app := &App{ gh_client_rw: client }
+  rule := &TriageRule{ Name: "all", Close: true, Component: "Blink>Layout",
+      Assign: []string{"someone"} }
+  if err := app.applyTriageRule(10, rule); err != nil {
+    t.Fatalf("applyTriageRule: %v", err)
+  }
+
+  // The comment explains the actions before their webhook events arrive.
+  want := []string{
+    "POST /repos/chromium-helper/csswg-resolutions/issues/10/comments",
+    "POST /repos/chromium-helper/csswg-resolutions/issues/10/labels",
+    "POST /repos/chromium-helper/csswg-resolutions/issues/10/assignees",
+    "PATCH /repos/chromium-helper/csswg-resolutions/issues/10",
+  }
+  if !reflect.DeepEqual(requests, want) {
+    t.Errorf("requests are %q, want %q", requests, want)
+  }
+}
@@ -17,6 +17,18 @@ const (
   Version = "1.1"
 
   lastRunTimeDoc = "last_run"
+  componentStatsDoc = "component_stats"
+  ownerStatsDoc = "owner_stats"
+  ccStatsDoc = "cc_stats"
+)
+
+// Triage outcomes, see FSResolutionData.TriageOutcome
+const (
+  TriageOutcomeNoAction = "no-action"
+  TriageOutcomeCrbugFiled = "crbug-filed"
+  TriageOutcomeCrbugUpdated = "crbug-updated"
+  TriageOutcomeDuplicate = "duplicate"
+  TriageOutcomeDeferred = "deferred"
 )
 
 // TODO(vmpstr): These need a good rename and a data wipe to support
@@ -34,10 +46,127 @@ type FSResolutionData struct {
   ResolutionCommentIds []int64 `firestore:"resolution-comment-ids,omitempty"`
   // True if there is a pending triage event
   HasPendingTriageEvents bool  `firestore:"has-pending-triage-events,omitempty"`
+  // Full name of the cloud task that will process the pending triage events.
+  // Older tasks for the same issue have been superseded by it.
+  PendingTaskName string       `firestore:"pending-task-name,omitempty"`
+  // Time of the first triage event that is still pending
+  PendingSince time.Time       `firestore:"pending-since,omitempty"`
+  // Time the pending task was last (re)scheduled
+  PendingUpdateTime time.Time  `firestore:"pending-update-time,omitempty"`
   // Comment ids in csswg-resolutions repo that were processed for triage
   TriagedCommentIds []int64    `firestore:"triaged-comment-ids,omitempty"`
+  // Comments whose commands failed. They run again once the comment is
+  // edited.
+  FailedComments []*FailedComment `firestore:"failed-comments,omitempty"`
+  // Crbug state as of the last sync with monorail
+  CrbugStatus string           `firestore:"crbug-status,omitempty"`
+  CrbugOwner string            `firestore:"crbug-owner,omitempty"`
+  CrbugMilestone string        `firestore:"crbug-milestone,omitempty"`
+  CrbugClosedTime time.Time    `firestore:"crbug-closed-time,omitempty"`
+  CrbugMergedInto int          `firestore:"crbug-merged-into,omitempty"`
+  CrbugSyncTime time.Time      `firestore:"crbug-sync-time,omitempty"`
+  // The crbug status that was last reported on the csswg-resolutions issue
+  CrbugReportedStatus string   `firestore:"crbug-reported-status,omitempty"`
+  // Components suggested to triagers when the issue was created
+  SuggestedComponents []string `firestore:"suggested-components,omitempty"`
+  // Owner and ccs suggested to triagers when the issue was created
+  SuggestedOwner string        `firestore:"suggested-owner,omitempty"`
+  SuggestedCcList []string     `firestore:"suggested-cc-list,omitempty"`
+  // Triage was deferred until this date by a /defer command
+  DeferredUntil time.Time      `firestore:"deferred-until,omitempty"`
+  // When the last deferral ended; the triage SLA counts from then
+  DeferralEndTime time.Time    `firestore:"deferral-end-time,omitempty"`
+  // How the issue was triaged (one of the TriageOutcome constants), the
+  // github login of the triager, when, and why if they said so
+  TriageOutcome string         `firestore:"triage-outcome,omitempty"`
+  TriageDecidedBy string       `firestore:"triage-decided-by,omitempty"`
+  TriageDecidedTime time.Time  `firestore:"triage-decided-time,omitempty"`
+  TriageReason string          `firestore:"triage-reason,omitempty"`
+  // When we last reminded triagers that this issue is past its triage SLA
+  SlaReminderTime time.Time    `firestore:"sla-reminder-time,omitempty"`
+  // Title and css-* labels of the csswg-drafts issue
+  Title string                 `firestore:"title,omitempty"`
+  SpecLabels []string          `firestore:"spec-labels,omitempty"`
+  // The recorded resolutions, in the order we saw them. Data recorded before
+  // we started storing these only has ResolutionCommentIds.
+  Resolutions []*RecordedResolution `firestore:"resolutions,omitempty"`
+  // LatestResolutionTime(), stored so that queries can order by it. The
+  // writes that change Resolutions keep it up to date.
+  LatestResolvedTime time.Time `firestore:"latest-resolved-time,omitempty"`
+  // Bugs that other engines filed for the csswg-drafts issue, as of the last
+  // peer tracker sync, and the comment on the csswg-resolutions issue that
+  // lists them
+  PeerBugs []*PeerBug          `firestore:"peer-bugs,omitempty"`
+  PeerBugsCommentId int64      `firestore:"peer-bugs-comment-id,omitempty"`
+  PeerSyncTime time.Time       `firestore:"peer-sync-time,omitempty"`
+  // web-platform-tests paths that reference the csswg-drafts issue, in their
+  // contents or commit messages, as of the last wpt scan, and the scan that
+  // last changed them
+  WptTests []string            `firestore:"wpt-tests,omitempty"`
+  WptScanTime time.Time        `firestore:"wpt-scan-time,omitempty"`
+  // The WptTests found in commit messages. Scans that only look at recent
+  // commits keep these while the tests exist.
+  WptCommitTests []string      `firestore:"wpt-commit-tests,omitempty"`
+  // Chromium source lines that mention the csswg-drafts issue, as of the last
+  // chromium scan, and the comment on the csswg-resolutions issue that lists
+  // them
+  ChromiumRefs []*ChromiumRef  `firestore:"chromium-refs,omitempty"`
+  ChromiumRefsCommentId int64  `firestore:"chromium-refs-comment-id,omitempty"`
+  ChromiumScanTime time.Time   `firestore:"chromium-scan-time,omitempty"`
+  // When the lines last changed, and when we last listed them on the crbug
+  ChromiumRefsChangeTime time.Time `firestore:"chromium-refs-change-time,omitempty"`
+  ChromiumRefsCrbugTime time.Time  `firestore:"chromium-refs-crbug-time,omitempty"`
+}
+
+// One csswg-drafts comment with resolutions in it.
+type RecordedResolution struct {
+  CommentId int64              `firestore:"comment-id,omitempty"`
+  CommentURL string            `firestore:"comment-url,omitempty"`
+  // The RESOLVED: lines
+  Text []string                `firestore:"text,omitempty"`
+  // When the comment was posted
+  Time time.Time               `firestore:"time,omitempty"`
+}
+
+// A triage comment whose commands failed, and when it was last edited then.
+type FailedComment struct {
+  CommentId int64              `firestore:"comment-id,omitempty"`
+  UpdatedTime time.Time        `firestore:"updated-time,omitempty"`
+}
+
+// A bug in another engine's tracker, see the peertrackers package.
+type PeerBug struct {
+  Tracker string               `firestore:"tracker,omitempty"`
+  Id int                       `firestore:"id,omitempty"`
+  Summary string               `firestore:"summary,omitempty"`
+  Status string                `firestore:"status,omitempty"`
+  Resolution string            `firestore:"resolution,omitempty"`
+  URL string                   `firestore:"url,omitempty"`
+}
+
+// A line in the Chromium source, see the chromiumrefs package.
+type ChromiumRef struct {
+  Path string                  `firestore:"path,omitempty"`
+  Line int                     `firestore:"line,omitempty"`
+  Text string                  `firestore:"text,omitempty"`
+}
+
+// Returns the time of the latest resolution, or zero if none are stored.
+func (d *FSResolutionData) LatestResolutionTime() time.Time {
+  var latest time.Time
+  for _, resolution := range d.Resolutions {
+    if resolution.Time.After(latest) {
+      latest = resolution.Time
+    }
+  }
+  return latest
 }
 
+// Number of times triagers picked each value (e.g. a component, owner or cc),
+// keyed by spec name (e.g. "css-grid") and then by value
+// (e.g. "Blink>Layout>Grid").
+type TriageStats map[string]map[string]int
+
 type Client struct {
   fsCollection string
   client *firestore.Client
@@ -106,6 +235,107 @@ func (c *Client) LoadDataByCsswgDraftsId(number int) (
   return loadDataFromQuery(query)
 }
 
+// Loads all the data in the collection.
+func (c *Client) LoadAllData() ([]*FSResolutionData, error) {
+  if c.client == nil {
+    return nil, fmt.Errorf("No firestore client")
+  }
+
+  // Only resolution docs have a csswg-resolutions id; this skips the stats and
+  // last run docs.
+  query := c.client.Collection(c.fsCollection).Where(
+      "csswg-resolutions-id", ">", 0)
+  return loadAllDataFromQuery(query)
+}
+
+// Loads all the data that has a crbug associated with it.
+func (c *Client) LoadDataWithCrbugs() ([]*FSResolutionData, error) {
+  if c.client == nil {
+    return nil, fmt.Errorf("No firestore client")
+  }
+
+  query := c.client.Collection(c.fsCollection).Where("crbug-id", ">", 0)
+  return loadAllDataFromQuery(query)
+}
+
+// Loads all the data that has pending triage events.
+func (c *Client) LoadDataWithPendingTriageEvents() ([]*FSResolutionData, error) {
+  if c.client == nil {
+    return nil, fmt.Errorf("No firestore client")
+  }
+
+  query := c.client.Collection(c.fsCollection).Where(
+      "has-pending-triage-events", "==", true)
+  return loadAllDataFromQuery(query)
+}
+
+// Loads the data whose triage was deferred until now or earlier.
+func (c *Client) LoadDataWithDueDeferrals(now time.Time) ([]*FSResolutionData, error) {
+  if c.client == nil {
+    return nil, fmt.Errorf("No firestore client")
+  }
+
+  query := c.client.Collection(c.fsCollection).Where("deferred-until", "<=", now)
+  return loadAllDataFromQuery(query)
+}
+
+// Loads the data with the latest resolutions first, stopping once limit of
+// them match. Data without stored resolutions is skipped.
+func (c *Client) LoadDataByLatestResolution(
+    match func(*FSResolutionData) bool, limit int) ([]*FSResolutionData, error) {
+  if c.client == nil {
+    return nil, fmt.Errorf("No firestore client")
+  }
+
+  query := c.client.Collection(c.fsCollection).OrderBy(
+      "latest-resolved-time", firestore.Desc)
+  iter := query.Documents(context.Background())
+  defer iter.Stop()
+
+  var results []*FSResolutionData
+  for len(results) < limit {
+    doc, err := iter.Next()
+    if err == iterator.Done {
+      break
+    }
+    if err != nil {
+      return nil, fmt.Errorf("iter.Next: %v", err)
+    }
+
+    var data FSResolutionData
+    if err = doc.DataTo(&data); err != nil {
+      return nil, fmt.Errorf("doc.DataTo: %v", err)
+    }
+    if match(&data) {
+      results = append(results, &data)
+    }
+  }
+  return results, nil
+}
+
+func loadAllDataFromQuery(query firestore.Query) ([]*FSResolutionData, error) {
+  iter := query.Documents(context.Background())
+  defer iter.Stop()
+
+  var results []*FSResolutionData
+  for {
+    doc, err := iter.Next()
+    if err == iterator.Done {
+      break
+    }
+    if err != nil {
+      return nil, fmt.Errorf("iter.Next: %v", err)
+    }
+
+    var data FSResolutionData
+    if err = doc.DataTo(&data); err != nil {
+      return nil, fmt.Errorf("doc.DataTo: %v", err)
+    }
+    results = append(results, &data)
+  }
+  return results, nil
+}
+
 func loadDataFromQuery(query firestore.Query) (*FSResolutionData, error) {
   iter := query.Documents(context.Background())
   doc, err := iter.Next()
@@ -134,6 +364,7 @@ func (c *Client) SetData(name string, data *FSResolutionData) error {
   if data.Version == "" {
     data.Version = Version
   }
+  data.LatestResolvedTime = data.LatestResolutionTime()
 
   if _, err := c.client.Collection(c.fsCollection).Doc(name).Set(
       context.Background(), data); err != nil {
@@ -148,18 +379,116 @@ func (c *Client) UpdateDataSetResolutionCommentIds(
     { Path: "resolution-comment-ids", Value: data.ResolutionCommentIds }})
 }
 
+func (c *Client) UpdateDataSetResolutions(
+    name string, data *FSResolutionData) error {
+  data.LatestResolvedTime = data.LatestResolutionTime()
+  return c.updateDataSetUpdate(name, []firestore.Update{
+    { Path: "resolution-comment-ids", Value: data.ResolutionCommentIds },
+    { Path: "resolutions", Value: data.Resolutions },
+    { Path: "latest-resolved-time", Value: data.LatestResolvedTime }})
+}
+
+func (c *Client) UpdateDataSetIssueInfo(
+    name string, data *FSResolutionData) error {
+  data.LatestResolvedTime = data.LatestResolutionTime()
+  return c.updateDataSetUpdate(name, []firestore.Update{
+    { Path: "title", Value: data.Title },
+    { Path: "spec-labels", Value: data.SpecLabels },
+    { Path: "resolutions", Value: data.Resolutions },
+    { Path: "latest-resolved-time", Value: data.LatestResolvedTime }})
+}
+
 func (c *Client) UpdateDataSetCrbugId(
     name string, data *FSResolutionData) error {
   return c.updateDataSetUpdate(name, []firestore.Update{
     { Path: "crbug-id", Value: data.CrbugId }})
 }
 
+// Sets the fields that triaging the issue changes, leaving the ones that the
+// webhook, the syncs and the cli keep to them.
+func (c *Client) UpdateDataSetTriage(
+    name string, data *FSResolutionData) error {
+  return c.updateDataSetUpdate(name, []firestore.Update{
+    { Path: "crbug-id", Value: data.CrbugId },
+    { Path: "triaged-comment-ids", Value: data.TriagedCommentIds },
+    { Path: "failed-comments", Value: data.FailedComments },
+    { Path: "deferred-until", Value: data.DeferredUntil },
+    { Path: "deferral-end-time", Value: data.DeferralEndTime },
+    { Path: "triage-outcome", Value: data.TriageOutcome },
+    { Path: "triage-decided-by", Value: data.TriageDecidedBy },
+    { Path: "triage-decided-time", Value: data.TriageDecidedTime },
+    { Path: "triage-reason", Value: data.TriageReason },
+    { Path: "chromium-refs-crbug-time", Value: data.ChromiumRefsCrbugTime }})
+}
+
+// Zero times are left out, so that a tracker that doesn't report them doesn't
+// clear the stored ones.
+func (c *Client) UpdateDataSetCrbugState(
+    name string, data *FSResolutionData) error {
+  updates := []firestore.Update{
+    { Path: "crbug-status", Value: data.CrbugStatus },
+    { Path: "crbug-owner", Value: data.CrbugOwner },
+    { Path: "crbug-milestone", Value: data.CrbugMilestone },
+    { Path: "crbug-merged-into", Value: data.CrbugMergedInto },
+    { Path: "crbug-reported-status", Value: data.CrbugReportedStatus }}
+  if !data.CrbugClosedTime.IsZero() {
+    updates = append(updates,
+        firestore.Update{ Path: "crbug-closed-time", Value: data.CrbugClosedTime })
+  }
+  if !data.CrbugSyncTime.IsZero() {
+    updates = append(updates,
+        firestore.Update{ Path: "crbug-sync-time", Value: data.CrbugSyncTime })
+  }
+  return c.updateDataSetUpdate(name, updates)
+}
+
 func (c *Client) UpdateDataSetHasPendingTriageEvents(
     name string, data *FSResolutionData) error {
   return c.updateDataSetUpdate(name, []firestore.Update{
     { Path: "has-pending-triage-events", Value: data.HasPendingTriageEvents }})
 }
 
+func (c *Client) UpdateDataSetPendingTask(
+    name string, data *FSResolutionData) error {
+  return c.updateDataSetUpdate(name, []firestore.Update{
+    { Path: "has-pending-triage-events", Value: data.HasPendingTriageEvents },
+    { Path: "pending-task-name", Value: data.PendingTaskName },
+    { Path: "pending-since", Value: data.PendingSince },
+    { Path: "pending-update-time", Value: data.PendingUpdateTime }})
+}
+
+func (c *Client) UpdateDataSetSlaReminderTime(
+    name string, data *FSResolutionData) error {
+  return c.updateDataSetUpdate(name, []firestore.Update{
+    { Path: "sla-reminder-time", Value: data.SlaReminderTime }})
+}
+
+func (c *Client) UpdateDataSetPeerBugs(
+    name string, data *FSResolutionData) error {
+  return c.updateDataSetUpdate(name, []firestore.Update{
+    { Path: "peer-bugs", Value: data.PeerBugs },
+    { Path: "peer-bugs-comment-id", Value: data.PeerBugsCommentId },
+    { Path: "peer-sync-time", Value: data.PeerSyncTime }})
+}
+
+func (c *Client) UpdateDataSetWptTests(
+    name string, data *FSResolutionData) error {
+  return c.updateDataSetUpdate(name, []firestore.Update{
+    { Path: "wpt-tests", Value: data.WptTests },
+    { Path: "wpt-scan-time", Value: data.WptScanTime },
+    { Path: "wpt-commit-tests", Value: data.WptCommitTests }})
+}
+
+func (c *Client) UpdateDataSetChromiumRefs(
+    name string, data *FSResolutionData) error {
+  return c.updateDataSetUpdate(name, []firestore.Update{
+    { Path: "chromium-refs", Value: data.ChromiumRefs },
+    { Path: "chromium-refs-comment-id", Value: data.ChromiumRefsCommentId },
+    { Path: "chromium-scan-time", Value: data.ChromiumScanTime },
+    { Path: "chromium-refs-change-time", Value: data.ChromiumRefsChangeTime },
+    { Path: "chromium-refs-crbug-time", Value: data.ChromiumRefsCrbugTime }})
+}
+
 func (c *Client) updateDataSetUpdate(
     name string, updates []firestore.Update) error {
   if c.client == nil {
@@ -208,3 +537,256 @@ func (c *Client) UpdateLastRunTime(t time.Time) error {
   }
   return nil
 }
+
+//-------------------- triage stats --------------------
+func (c *Client) LoadComponentStats() (TriageStats, error) {
+  return c.loadStats(componentStatsDoc)
+}
+
+func (c *Client) LoadOwnerStats() (TriageStats, error) {
+  return c.loadStats(ownerStatsDoc)
+}
+
+func (c *Client) LoadCcStats() (TriageStats, error) {
+  return c.loadStats(ccStatsDoc)
+}
+
+// Records that the given components were picked for an issue with the given
+// specs.
+func (c *Client) RecordComponentChoices(specs, components []string) error {
+  return c.recordStats(componentStatsDoc, specs, components)
+}
+
+func (c *Client) RecordOwnerChoices(specs, owners []string) error {
+  return c.recordStats(ownerStatsDoc, specs, owners)
+}
+
+func (c *Client) RecordCcChoices(specs, ccs []string) error {
+  return c.recordStats(ccStatsDoc, specs, ccs)
+}
+
+func (c *Client) loadStats(name string) (TriageStats, error) {
+  if c.client == nil {
+    return nil, fmt.Errorf("No firestore client")
+  }
+
+  docsnap, err := c.client.Collection(c.fsCollection).Doc(name).
+      Get(context.Background())
+  if err != nil {
+    if status.Code(err) == codes.NotFound {
+      return TriageStats{}, nil
+    }
+    return nil, fmt.Errorf("get: %v", err)
+  }
+
+  var data struct { Stats TriageStats `firestore:"stats"` }
+  if err = docsnap.DataTo(&data); err != nil {
+    return nil, fmt.Errorf("docsnap.DataTo: %v", err)
+  }
+  if data.Stats == nil {
+    data.Stats = TriageStats{}
+  }
+  return data.Stats, nil
+}
+
+func (c *Client) recordStats(name string, specs, values []string) error {
+  if c.client == nil {
+    return fmt.Errorf("No firestore client")
+  }
+
+  doc := c.client.Collection(c.fsCollection).Doc(name)
+  err := c.client.RunTransaction(context.Background(),
+      func(ctx context.Context, tx *firestore.Transaction) error {
+    var data struct { Stats TriageStats `firestore:"stats"` }
+    docsnap, err := tx.Get(doc)
+    if err != nil && status.Code(err) != codes.NotFound {
+      return fmt.Errorf("tx.Get: %v", err)
+    }
+    if err == nil {
+      if err = docsnap.DataTo(&data); err != nil {
+        return fmt.Errorf("docsnap.DataTo: %v", err)
+      }
+    }
+    if data.Stats == nil {
+      data.Stats = TriageStats{}
+    }
+
+    for _, spec := range specs {
+      if data.Stats[spec] == nil {
+        data.Stats[spec] = make(map[string]int)
+      }
+      for _, value := range values {
+        data.Stats[spec][value]++
+      }
+    }
+    return tx.Set(doc, &data)
+  })
+  if err != nil {
+    return fmt.Errorf("RunTransaction: %v", err)
+  }
+  return nil
+}
+
+//-------------------- webhook deliveries --------------------
+// A github webhook delivery, keyed by its X-GitHub-Delivery id. These live in
+// a separate "<collection>-webhook-deliveries" collection.
+type WebhookDelivery struct {
+  // The X-GitHub-Event type, e.g. "issue_comment"
+  Event string                 `firestore:"event"`
+  Payload []byte               `firestore:"payload"`
+  ReceivedTime time.Time       `firestore:"received-time"`
+  // Set once the delivery was processed successfully
+  ProcessedTime time.Time      `firestore:"processed-time,omitempty"`
+  // The error from the last attempt to process the delivery, if any
+  Error string                 `firestore:"error,omitempty"`
+}
+
+func (c *Client) deliveriesCollection() *firestore.CollectionRef {
+  return c.client.Collection(c.fsCollection + "-webhook-deliveries")
+}
+
+// Returns nil if the delivery wasn't recorded.
+func (c *Client) LoadWebhookDelivery(id string) (*WebhookDelivery, error) {
+  if c.client == nil {
+    return nil, fmt.Errorf("No firestore client")
+  }
+
+  docsnap, err := c.deliveriesCollection().Doc(id).Get(context.Background())
+  if err != nil {
+    if status.Code(err) == codes.NotFound {
+      return nil, nil
+    }
+    return nil, fmt.Errorf("get: %v", err)
+  }
+
+  var delivery WebhookDelivery
+  if err = docsnap.DataTo(&delivery); err != nil {
+    return nil, fmt.Errorf("docsnap.DataTo: %v", err)
+  }
+  return &delivery, nil
+}
+
+// Records the delivery, claiming it for processing. If a delivery with the id
+// was already recorded, e.g. because github redelivered it, it is only
+// replaced if reclaim returns true for it. Returns whether the delivery was
+// claimed.
+func (c *Client) ClaimWebhookDelivery(id string, delivery *WebhookDelivery,
+    reclaim func(previous *WebhookDelivery) bool) (bool, error) {
+  if c.client == nil {
+    return false, fmt.Errorf("No firestore client")
+  }
+
+  doc := c.deliveriesCollection().Doc(id)
+  claimed := false
+  err := c.client.RunTransaction(context.Background(),
+      func(ctx context.Context, tx *firestore.Transaction) error {
+    claimed = false
+    docsnap, err := tx.Get(doc)
+    if err != nil && status.Code(err) != codes.NotFound {
+      return fmt.Errorf("tx.Get: %v", err)
+    }
+    if err == nil {
+      var previous WebhookDelivery
+      if err = docsnap.DataTo(&previous); err != nil {
+        return fmt.Errorf("docsnap.DataTo: %v", err)
+      }
+      if !reclaim(&previous) {
+        return nil
+      }
+    }
+    claimed = true
+    return tx.Set(doc, delivery)
+  })
+  if err != nil {
+    return false, fmt.Errorf("RunTransaction: %v", err)
+  }
+  return claimed, nil
+}
+
+// Deletes the deliveries received before t, and returns how many there were.
+func (c *Client) DeleteWebhookDeliveriesBefore(t time.Time) (int, error) {
+  if c.client == nil {
+    return 0, fmt.Errorf("No firestore client")
+  }
+
+  ctx := context.Background()
+  iter := c.deliveriesCollection().Where("received-time", "<", t).Documents(ctx)
+  defer iter.Stop()
+
+  deleted := 0
+  for {
+    doc, err := iter.Next()
+    if err == iterator.Done {
+      break
+    }
+    if err != nil {
+      return deleted, fmt.Errorf("iter.Next: %v", err)
+    }
+    if _, err := doc.Ref.Delete(ctx); err != nil {
+      return deleted, fmt.Errorf("delete %s: %v", doc.Ref.ID, err)
+    }
+    deleted++
+  }
+  return deleted, nil
+}
+
+func (c *Client) SetWebhookDelivery(id string, delivery *WebhookDelivery) error {
+  if c.client == nil {
+    return fmt.Errorf("No firestore client")
+  }
+
+  if _, err := c.deliveriesCollection().Doc(id).Set(
+      context.Background(), delivery); err != nil {
+    return fmt.Errorf("set: %v", err)
+  }
+  return nil
+}
+
+//-------------------- collaborator permissions --------------------
+// Cached github permission of a user on the csswg-resolutions repo. These live
+// in a separate "<collection>-collaborators" collection, keyed by login.
+type CollaboratorPermission struct {
+  // One of "admin", "write", "read" or "none"
+  Permission string             `firestore:"permission"`
+  FetchTime time.Time           `firestore:"fetch-time"`
+}
+
+func (c *Client) collaboratorsCollection() *firestore.CollectionRef {
+  return c.client.Collection(c.fsCollection + "-collaborators")
+}
+
+// Returns nil if the permission wasn't cached.
+func (c *Client) LoadCollaboratorPermission(login string) (
+    *CollaboratorPermission, error) {
+  if c.client == nil {
+    return nil, fmt.Errorf("No firestore client")
+  }
+
+  docsnap, err := c.collaboratorsCollection().Doc(login).
+      Get(context.Background())
+  if err != nil {
+    if status.Code(err) == codes.NotFound {
+      return nil, nil
+    }
+    return nil, fmt.Errorf("get: %v", err)
+  }
+
+  var permission CollaboratorPermission
+  if err = docsnap.DataTo(&permission); err != nil {
+    return nil, fmt.Errorf("docsnap.DataTo: %v", err)
+  }
+  return &permission, nil
+}
+
+func (c *Client) SetCollaboratorPermission(
+    login string, permission *CollaboratorPermission) error {
+  if c.client == nil {
+    return fmt.Errorf("No firestore client")
+  }
+
+  if _, err := c.collaboratorsCollection().Doc(login).Set(
+      context.Background(), permission); err != nil {
+    return fmt.Errorf("set: %v", err)
+  }
+  return nil
+}
@@ -0,0 +1,7 @@
+module github.com/chromium-helper/csswg-resolutions/listcomments
+
+go 1.19
+
+require github.com/google/go-github v17.0.0+incompatible
+
+require github.com/google/go-querystring v1.1.0 // indirect
@@ -0,0 +1,58 @@
+// Package listcomments keeps comments on csswg-resolutions issues that list
+// things about the csswg-drafts issue, e.g. other engines' bugs or Chromium
+// code, up to date.
+package listcomments
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Marks list comments, so that the webhook doesn't take them for triage
+// comments, whoever posts them.
+const Marker = "<!-- csswg-resolutions list comment -->"
+
+// Returns whether the comment body is a list comment.
+func IsListComment(body string) bool {
+	return strings.Contains(body, Marker)
+}
+
+// Returns the body of a list comment. what describes the items and mentions
+// the csswg-drafts issue, e.g. "Chromium code that mentions <link>", and list
+// is the markdown list or table, empty if nothing is left.
+func Text(what, list string, checked time.Time) string {
+	if list == "" {
+		return fmt.Sprintf("%s\nFound no %s anymore.", Marker, what)
+	}
+	return fmt.Sprintf("%s\nFound %s:\n\n%s\n"+
+		"This comment is updated when that changes (last checked %s).",
+		Marker, what, list, checked.UTC().Format("2006-01-02"))
+}
+
+// Creates the list comment on the issue if comment_id is 0, or edits it.
+// Doesn't start a comment when there is nothing to list. Returns the id of
+// the comment, or 0 if there is none.
+func Update(ctx context.Context, client *github.Client, owner, repo string,
+	issue int, comment_id int64, what, list string, checked time.Time) (int64, error) {
+	if comment_id == 0 && list == "" {
+		return 0, nil
+	}
+	body := Text(what, list, checked)
+	comment := &github.IssueComment{Body: &body}
+	if comment_id == 0 {
+		created, _, err := client.Issues.CreateComment(ctx, owner, repo, issue, comment)
+		if err != nil {
+			return 0, fmt.Errorf("Issues.CreateComment: %v", err)
+		}
+		return created.GetID(), nil
+	}
+	_, _, err := client.Issues.EditComment(ctx, owner, repo, comment_id, comment)
+	if err != nil {
+		return comment_id, fmt.Errorf("Issues.EditComment: %v", err)
+	}
+	return comment_id, nil
+}
@@ -0,0 +1,86 @@
+package listcomments
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Returns a client for a GitHub server that records "METHOD path" of each
+// request and answers with comment id 42.
+func newTestClient(t *testing.T) (*github.Client, *[]string) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		id := int64(42)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&github.IssueComment{ID: &id})
+	}))
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	base_url, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	client.BaseURL = base_url
+	return client, &requests
+}
+
+func TestText(t *testing.T) {
+	checked := time.Date(2026, 3, 4, 23, 0, 0, 0, time.UTC)
+	text := Text("Chromium code that mentions #5", "* a.cc:1", checked)
+	if !IsListComment(text) {
+		t.Errorf("%q isn't a list comment", text)
+	}
+	for _, want := range []string{"Found Chromium code that mentions #5:", "* a.cc:1", "last checked 2026-03-04"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("%q doesn't contain %q", text, want)
+		}
+	}
+
+	empty := Text("Chromium code that mentions #5", "", checked)
+	if !IsListComment(empty) || !strings.Contains(empty, "Found no Chromium code that mentions #5 anymore.") {
+		t.Errorf("empty list text is %q", empty)
+	}
+	if IsListComment("/triage") {
+		t.Errorf("a triage comment is a list comment")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		comment_id int64
+		list       string
+		want_id    int64
+		want       string
+	}{
+		{"create", 0, "* a.cc:1", 42, "POST /repos/o/r/issues/10/comments"},
+		{"edit", 7, "* a.cc:1", 7, "PATCH /repos/o/r/issues/comments/7"},
+		{"edit to empty", 7, "", 7, "PATCH /repos/o/r/issues/comments/7"},
+		{"nothing to list", 0, "", 0, ""},
+	}
+	for _, test := range tests {
+		client, requests := newTestClient(t)
+		id, err := Update(context.Background(), client, "o", "r", 10, test.comment_id,
+			"code", test.list, time.Now())
+		if err != nil {
+			t.Errorf("%s: Update: %v", test.name, err)
+			continue
+		}
+		if id != test.want_id {
+			t.Errorf("%s: Update returned id %d, want %d", test.name, id, test.want_id)
+		}
+		if got := strings.Join(*requests, ","); got != test.want {
+			t.Errorf("%s: requests are %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package triage_task_handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chromium-helper/csswg-resolutions/monorail"
+)
+
+func TestNewBugTrackerIssueTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "components.json")
+	if err := os.WriteFile(path, []byte(`{"Blink>Layout": 1456332}`), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	bugTracker = "issuetracker"
+	issueTrackerComponentIdsFile = path
+	defer func() { bugTracker, issueTrackerComponentIdsFile = "", "" }()
+
+	tracker, err := NewBugTracker()
+	if err != nil {
+		t.Fatalf("NewBugTracker: %v", err)
+	}
+	service, ok := tracker.(*monorail.IssueTrackerService)
+	if !ok {
+		t.Fatalf("NewBugTracker returned a %T, want *monorail.IssueTrackerService", tracker)
+	}
+	if service.ComponentIds["Blink>Layout"] != 1456332 {
+		t.Errorf("ComponentIds are %v, want the ones from %s", service.ComponentIds, path)
+	}
+}
+
+func TestNewBugTrackerErrors(t *testing.T) {
+	defer func() { bugTracker, issueTrackerComponentIdsFile = "", "" }()
+
+	bugTracker = "bugzilla"
+	if _, err := NewBugTracker(); err == nil {
+		t.Errorf("NewBugTracker succeeded for an unknown BUG_TRACKER")
+	}
+
+	bugTracker = "issuetracker"
+	issueTrackerComponentIdsFile = filepath.Join(t.TempDir(), "missing.json")
+	if _, err := NewBugTracker(); err == nil {
+		t.Errorf("NewBugTracker succeeded without component ids")
+	}
+}
@@ -0,0 +1,49 @@
+package triage_task_handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/chromiumrefs"
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/chromium-helper/csswg-resolutions/monorail"
+)
+
+func chromiumRefsText(stored []*fsresolutions.ChromiumRef) string {
+	if len(stored) == 0 {
+		return ""
+	}
+	return "Chromium code that mentions the csswg-drafts issue:\n" +
+		chromiumrefs.Text(chromiumrefs.FromStored(stored)) + "\n"
+}
+
+// Lists the Chromium code that mentions the csswg-drafts issue on the crbug,
+// if it changed since we last did. The chromium scan in resolutions-cli
+// records the changes; crbugs filed after a scan already have the list in
+// their description.
+func (app *App) ReportChromiumRefs(fsdata *fsresolutions.FSResolutionData) error {
+	if fsdata.CrbugId == 0 || len(fsdata.ChromiumRefs) == 0 ||
+		!fsdata.ChromiumRefsChangeTime.After(fsdata.ChromiumRefsCrbugTime) ||
+		!fsdata.CrbugClosedTime.IsZero() {
+		return nil
+	}
+
+	comment := fmt.Sprintf("The Chromium code that mentions "+
+		"https://github.com/w3c/csswg-drafts/issues/%d changed.\n\n%s",
+		fsdata.CsswgDraftsId, chromiumRefsText(fsdata.ChromiumRefs))
+	err := app.BugTracker.ModifyIssue(&monorail.ModifyIssueRequest{
+		Project: "chromium",
+		Crbug:   fsdata.CrbugId,
+		Comment: comment,
+	})
+	if err != nil {
+		return fmt.Errorf("ModifyIssue: %v", err)
+	}
+
+	fsdata.ChromiumRefsCrbugTime = time.Now()
+	err = app.FSClient.UpdateDataSetChromiumRefs(fileNameFromData(fsdata), fsdata)
+	if err != nil {
+		return fmt.Errorf("UpdateDataSetChromiumRefs: %v", err)
+	}
+	return nil
+}
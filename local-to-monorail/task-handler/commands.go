@@ -0,0 +1,439 @@
+package triage_task_handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/google/go-github/github"
+)
+
+// Slash commands, which must start a comment line:
+//
+//	/crbug Blink>Layout owner=foo cc=a,b pri=1   file a crbug
+//	/crbug 12345                                 update an existing crbug
+//	/close no-action [reason]                    close without filing a crbug
+//	/dup 1234                                    duplicate of another tracking issue
+//	/defer 2026-12-01                            come back to this later
+//	/snooze 2w                                   same, but relative (d or w)
+//
+// "defer until 2026-12-01" on its own line also works.
+const (
+	crbugCommand  = "crbug"
+	closeCommand  = "close"
+	dupCommand    = "dup"
+	deferCommand  = "defer"
+	snoozeCommand = "snooze"
+
+	noActionReason  = "no-action"
+	deferDateLayout = "2006-01-02"
+)
+
+// Reactions on the command comment: seen when we start processing it, then
+// either done or failed.
+const (
+	reactionSeen   = "eyes"
+	reactionDone   = "rocket"
+	reactionFailed = "confused"
+)
+
+var crbugIdRegexp = regexp.MustCompile(`^[0-9]{5,}$`)
+var snoozeRegexp = regexp.MustCompile(`^([0-9]+)([dw])$`)
+
+type Command struct {
+	Name    string
+	Line    string
+	Comment *github.IssueComment
+
+	Components  []string
+	Crbug       int
+	Owner       string
+	CcList      []string
+	Priority    string
+	CloseReason string
+	DuplicateOf int
+	DeferUntil  time.Time
+
+	// Set if the command could not be parsed or used
+	Err error
+}
+
+// Returns the directive names the command needs permission for, as used in
+// the "directives" section of the triagers config.
+func (c *Command) DirectiveNames() []string {
+	names := []string{c.Name}
+	if c.Owner != "" {
+		names = append(names, "owner")
+	}
+	if len(c.CcList) != 0 {
+		names = append(names, "cc")
+	}
+	return names
+}
+
+// Turns "foo" into "foo@chromium.org", leaving full emails alone.
+func userEmail(input string) string {
+	user := strings.Trim(input, " \n\r")
+	if !strings.Contains(user, "@") {
+		user += "@chromium.org"
+	}
+	return user
+}
+
+// Parses a comment line. Returns nil if the line is not a slash command, and a
+// command with Err set if it is malformed.
+func ParseCommand(line string, now time.Time) *Command {
+	fields := strings.Fields(line)
+	if len(fields) >= 2 && strings.ToLower(fields[0]) == deferCommand && strings.ToLower(fields[1]) == "until" {
+		fields = append([]string{"/" + deferCommand}, fields[2:]...)
+	}
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return nil
+	}
+
+	command := &Command{
+		Name: strings.ToLower(fields[0][1:]),
+		Line: strings.TrimSpace(line),
+	}
+	args := fields[1:]
+	switch command.Name {
+	case crbugCommand:
+		command.Err = parseCrbugArgs(command, args)
+	case closeCommand:
+		if len(args) == 0 || strings.ToLower(args[0]) != noActionReason {
+			command.Err = fmt.Errorf("expected `/close %s [reason]`", noActionReason)
+		} else {
+			command.CloseReason = strings.Join(args[1:], " ")
+		}
+	case dupCommand:
+		if len(args) != 1 {
+			command.Err = fmt.Errorf("expected `/dup <issue number>`")
+			break
+		}
+		number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil || number <= 0 {
+			command.Err = fmt.Errorf("`%s` is not an issue number", args[0])
+		}
+		command.DuplicateOf = number
+	case deferCommand, snoozeCommand:
+		// Both are deferrals; only the way the date is given differs.
+		command.Name = deferCommand
+		if len(args) != 1 {
+			command.Err = fmt.Errorf("expected `/defer YYYY-MM-DD` or `/snooze <N>d|<N>w`")
+			break
+		}
+		command.DeferUntil, command.Err = parseDeferDate(args[0], now)
+	default:
+		return nil
+	}
+	return command
+}
+
+// Parses a YYYY-MM-DD date, or a number of days or weeks from now.
+func parseDeferDate(arg string, now time.Time) (time.Time, error) {
+	var date time.Time
+	if match := snoozeRegexp.FindStringSubmatch(strings.ToLower(arg)); match != nil {
+		count, _ := strconv.Atoi(match[1])
+		if match[2] == "w" {
+			count *= 7
+		}
+		date = now.AddDate(0, 0, count)
+	} else {
+		var err error
+		date, err = time.Parse(deferDateLayout, arg)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("`%s` is not a YYYY-MM-DD date, or a number of days or weeks", arg)
+		}
+	}
+	if !date.After(now) {
+		return time.Time{}, fmt.Errorf("%s is not in the future", date.Format(deferDateLayout))
+	}
+	return date, nil
+}
+
+func parseCrbugArgs(command *Command, args []string) error {
+	for _, arg := range args {
+		key, value, is_option := strings.Cut(arg, "=")
+		if !is_option {
+			if crbugIdRegexp.MatchString(arg) {
+				command.Crbug, _ = strconv.Atoi(arg)
+			} else {
+				command.Components = append(command.Components, arg)
+			}
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "owner":
+			command.Owner = userEmail(value)
+		case "cc":
+			for _, cc := range strings.Split(value, ",") {
+				if cc != "" {
+					command.CcList = append(command.CcList, userEmail(cc))
+				}
+			}
+		case "pri":
+			if priority, err := strconv.Atoi(value); err != nil || priority < 0 || priority > 3 {
+				return fmt.Errorf("`pri` must be 0 to 3, not `%s`", value)
+			}
+			command.Priority = value
+		default:
+			return fmt.Errorf("unknown option `%s`", key)
+		}
+	}
+
+	if len(command.Components) == 0 && command.Crbug == 0 {
+		return fmt.Errorf("expected a component or a crbug id")
+	}
+	if len(command.Components) != 0 && command.Crbug != 0 {
+		return fmt.Errorf("expected either components or a crbug id, not both")
+	}
+	return nil
+}
+
+// Applies a command on top of the directive. Later commands win.
+func (directive *Directive) ApplyCommand(command *Command) {
+	directive.Commands = append(directive.Commands, command)
+	if command.Err != nil {
+		return
+	}
+
+	directive.Action = command.Name
+	directive.ActionCommand = command
+	directive.DecidedBy = command.Comment.GetUser().GetLogin()
+	switch command.Name {
+	case crbugCommand:
+		directive.Components = command.Components
+		directive.Crbug = command.Crbug
+		if command.Owner != "" {
+			directive.Owner = command.Owner
+		}
+		if len(command.CcList) != 0 {
+			directive.CcList = command.CcList
+		}
+		directive.Priority = command.Priority
+	case closeCommand:
+		directive.CloseReason = command.CloseReason
+	case dupCommand:
+		directive.DuplicateOf = command.DuplicateOf
+	case deferCommand:
+		directive.DeferUntil = command.DeferUntil
+	}
+}
+
+func deferredLabel() string {
+	if deferredLabelName == "" {
+		return "deferred"
+	}
+	return deferredLabelName
+}
+
+// Returns whether the command's comment was handled: it ran, or it failed
+// and hasn't been edited since.
+func isHandledCommand(fsdata *fsresolutions.FSResolutionData, command *Command) bool {
+	for _, id := range fsdata.TriagedCommentIds {
+		if id == command.Comment.GetID() {
+			return true
+		}
+	}
+	for _, failed := range fsdata.FailedComments {
+		if failed.CommentId == command.Comment.GetID() {
+			return !command.Comment.GetUpdatedAt().After(failed.UpdatedTime)
+		}
+	}
+	return false
+}
+
+// Records the comment as handled: in TriagedCommentIds if its commands ran,
+// or in FailedComments so that an edit runs them again.
+func recordHandledComment(fsdata *fsresolutions.FSResolutionData, comment *github.IssueComment, succeeded bool) {
+	var failed_comments []*fsresolutions.FailedComment
+	for _, failed := range fsdata.FailedComments {
+		if failed.CommentId != comment.GetID() {
+			failed_comments = append(failed_comments, failed)
+		}
+	}
+	fsdata.FailedComments = failed_comments
+
+	if !succeeded {
+		fsdata.FailedComments = append(fsdata.FailedComments, &fsresolutions.FailedComment{
+			CommentId:   comment.GetID(),
+			UpdatedTime: comment.GetUpdatedAt(),
+		})
+		return
+	}
+	for _, id := range fsdata.TriagedCommentIds {
+		if id == comment.GetID() {
+			return
+		}
+	}
+	fsdata.TriagedCommentIds = append(fsdata.TriagedCommentIds, comment.GetID())
+}
+
+// Returns the commands whose comments we haven't acknowledged yet.
+func newCommands(fsdata *fsresolutions.FSResolutionData, commands []*Command) []*Command {
+	var result []*Command
+	for _, command := range commands {
+		if !isHandledCommand(fsdata, command) {
+			result = append(result, command)
+		}
+	}
+	return result
+}
+
+func (app *App) reactToCommand(command *Command, reaction string) {
+	_, _, err := app.GithubClient.Reactions.CreateIssueCommentReaction(
+		context.Background(), githubLogin, githubRepo, command.Comment.GetID(), reaction)
+	if err != nil {
+		log.Printf("ERROR: CreateIssueCommentReaction: %v\n", err)
+	}
+}
+
+// Marks the commands as handled, reacting to each depending on whether it
+// (and the triage as a whole) succeeded. Commands that could not be parsed get
+// a reply explaining why. Failed commands run again if their comment is
+// edited.
+func (app *App) ReportCommands(fsdata *fsresolutions.FSResolutionData, ghissue *github.Issue, commands []*Command, ok bool) {
+	// A comment may hold several commands; it only counts as run if they all
+	// did.
+	succeeded := make(map[int64]bool)
+	var comments []*github.IssueComment
+	for _, command := range commands {
+		id := command.Comment.GetID()
+		if _, seen := succeeded[id]; !seen {
+			succeeded[id] = true
+			comments = append(comments, command.Comment)
+		}
+		if command.Err != nil || !ok {
+			succeeded[id] = false
+		}
+	}
+	for _, comment := range comments {
+		recordHandledComment(fsdata, comment, succeeded[comment.GetID()])
+	}
+
+	for _, command := range commands {
+		if command.Err == nil {
+			if ok {
+				app.reactToCommand(command, reactionDone)
+			} else {
+				app.reactToCommand(command, reactionFailed)
+			}
+			continue
+		}
+
+		app.reactToCommand(command, reactionFailed)
+		comment_text := fmt.Sprintf("> %s\n\n@%s I could not run this command: %v", command.Line, command.Comment.GetUser().GetLogin(), command.Err)
+		comment := &github.IssueComment{Body: &comment_text}
+		_, _, err := app.GithubClient.Issues.CreateComment(
+			context.Background(), githubLogin, githubRepo, ghissue.GetNumber(), comment)
+		if err != nil {
+			log.Printf("ERROR: Issues.CreateComment: %v\n", err)
+		}
+	}
+}
+
+// Carries out /close, /dup and /defer. /crbug goes through the usual crbug
+// filing path.
+func (app *App) RunAction(fsdata *fsresolutions.FSResolutionData, ghissue *github.Issue, directive *Directive) error {
+	switch directive.Action {
+	case closeCommand:
+		app.RecordOutcome(fsdata, ghissue, fsresolutions.TriageOutcomeNoAction, directive.DecidedBy, directive.CloseReason)
+		comment_text := "Closing, since no action is needed in Chromium."
+		if directive.CloseReason != "" {
+			comment_text += fmt.Sprintf("\n\nReason: %s", directive.CloseReason)
+		}
+		return app.CommentAndCloseWithText(ghissue, comment_text)
+	case dupCommand:
+		app.RecordOutcome(fsdata, ghissue, fsresolutions.TriageOutcomeDuplicate, directive.DecidedBy, fmt.Sprintf("Duplicate of #%d", directive.DuplicateOf))
+		comment_text := fmt.Sprintf("Duplicate of #%d, closing issue.", directive.DuplicateOf)
+		return app.CommentAndCloseWithText(ghissue, comment_text)
+	case deferCommand:
+		_, _, err := app.GithubClient.Issues.AddLabelsToIssue(
+			context.Background(), githubLogin, githubRepo, ghissue.GetNumber(), []string{deferredLabel()})
+		if err != nil {
+			return fmt.Errorf("Issues.AddLabelsToIssue: %v", err)
+		}
+		fsdata.DeferredUntil = directive.DeferUntil
+		app.RecordOutcome(fsdata, ghissue, fsresolutions.TriageOutcomeDeferred, directive.DecidedBy, fmt.Sprintf("Deferred until %s", directive.DeferUntil.Format(deferDateLayout)))
+		comment_text := fmt.Sprintf("Triage of this issue is deferred until %s, when I'll remind @%s to take another look.", directive.DeferUntil.Format(deferDateLayout), directive.DecidedBy)
+		comment := &github.IssueComment{Body: &comment_text}
+		_, _, err = app.GithubClient.Issues.CreateComment(
+			context.Background(), githubLogin, githubRepo, ghissue.GetNumber(), comment)
+		if err != nil {
+			return fmt.Errorf("Issues.CreateComment: %v", err)
+		}
+	}
+	return nil
+}
+
+func (app *App) removeDeferredLabel(ghissue *github.Issue) error {
+	for _, label := range ghissue.Labels {
+		if label.GetName() != deferredLabel() {
+			continue
+		}
+		_, err := app.GithubClient.Issues.RemoveLabelForIssue(
+			context.Background(), githubLogin, githubRepo, ghissue.GetNumber(), deferredLabel())
+		if err != nil {
+			return fmt.Errorf("Issues.RemoveLabelForIssue: %v", err)
+		}
+	}
+	return nil
+}
+
+// Drops a pending deferral without reminding anyone, because the issue was
+// triaged some other way. Failing to remove the label is only logged, since
+// the deferral itself is gone.
+func (app *App) ClearDeferral(fsdata *fsresolutions.FSResolutionData, ghissue *github.Issue) {
+	if fsdata.DeferredUntil.IsZero() {
+		return
+	}
+	fsdata.DeferredUntil = time.Time{}
+	if err := app.removeDeferredLabel(ghissue); err != nil {
+		log.Printf("ERROR: removeDeferredLabel: %v\n", err)
+	}
+}
+
+// Brings a deferred issue back for triage: reopens it if needed, removes the
+// deferred label and reminds whoever deferred it. Returns the updated issue.
+func (app *App) EndDeferral(fsdata *fsresolutions.FSResolutionData, ghissue *github.Issue) (*github.Issue, error) {
+	ctx := context.Background()
+	if ghissue.GetState() == "closed" {
+		new_state := "open"
+		issue, _, err := app.GithubClient.Issues.Edit(
+			ctx, githubLogin, githubRepo, ghissue.GetNumber(), &github.IssueRequest{State: &new_state})
+		if err != nil {
+			return nil, fmt.Errorf("Issues.Edit: %v", err)
+		}
+		ghissue = issue
+	}
+
+	if err := app.removeDeferredLabel(ghissue); err != nil {
+		return nil, err
+	}
+
+	comment_text := fmt.Sprintf("Triage of this issue was deferred until %s, so it's time to take another look.", fsdata.DeferredUntil.Format(deferDateLayout))
+	if fsdata.TriageDecidedBy != "" {
+		comment_text = fmt.Sprintf("@%s %s", fsdata.TriageDecidedBy, comment_text)
+	}
+	comment := &github.IssueComment{Body: &comment_text}
+	_, _, err := app.GithubClient.Issues.CreateComment(
+		ctx, githubLogin, githubRepo, ghissue.GetNumber(), comment)
+	if err != nil {
+		return nil, fmt.Errorf("Issues.CreateComment: %v", err)
+	}
+
+	fsdata.DeferralEndTime = fsdata.DeferredUntil
+	fsdata.DeferredUntil = time.Time{}
+	// The issue is untriaged again.
+	if fsdata.TriageOutcome == fsresolutions.TriageOutcomeDeferred {
+		fsdata.TriageOutcome = ""
+		fsdata.TriageReason = ""
+	}
+	return ghissue, nil
+}
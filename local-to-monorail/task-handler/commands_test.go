@@ -0,0 +1,86 @@
+package triage_task_handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/google/go-github/github"
+)
+
+func newTestComment(id int64, body string, updated time.Time) *github.IssueComment {
+	login := "triager"
+	return &github.IssueComment{ID: &id, Body: &body, UpdatedAt: &updated, User: &github.User{Login: &login}}
+}
+
+func parseTestCommand(t *testing.T, comment *github.IssueComment) *Command {
+	command := ParseCommand(comment.GetBody(), time.Now())
+	if command == nil {
+		t.Fatalf("%q is not a command", comment.GetBody())
+	}
+	command.Comment = comment
+	return command
+}
+
+func TestFailedCommandRunsAgainWhenEdited(t *testing.T) {
+	_, client := newFakeGithub(t)
+	app := &App{GithubClient: client}
+	fsdata := &fsresolutions.FSResolutionData{CsswgResolutionsId: 10}
+	issue := &github.Issue{Number: github.Int(10)}
+	created := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// A typo in the option fails to parse.
+	failed := parseTestCommand(t, newTestComment(7, "/crbug Blink>Layout pri=9", created))
+	if failed.Err == nil {
+		t.Fatalf("pri=9 parsed")
+	}
+	app.ReportCommands(fsdata, issue, []*Command{failed}, true)
+	if !isHandledCommand(fsdata, failed) {
+		t.Errorf("an unedited failed command would run again")
+	}
+	if len(fsdata.TriagedCommentIds) != 0 {
+		t.Errorf("TriagedCommentIds is %v, want the failed comment left out", fsdata.TriagedCommentIds)
+	}
+
+	// Fixing the comment makes it new again.
+	edited := parseTestCommand(t, newTestComment(7, "/crbug Blink>Layout pri=1", created.Add(time.Hour)))
+	if commands := newCommands(fsdata, []*Command{edited}); len(commands) != 1 {
+		t.Fatalf("newCommands returned %d commands, want the edited one", len(commands))
+	}
+	app.ReportCommands(fsdata, issue, []*Command{edited}, true)
+	if !isHandledCommand(fsdata, edited) || len(fsdata.FailedComments) != 0 {
+		t.Errorf("after running, TriagedCommentIds is %v and FailedComments %v",
+			fsdata.TriagedCommentIds, fsdata.FailedComments)
+	}
+}
+
+func TestFailedTriageRunsAgainWhenEdited(t *testing.T) {
+	_, client := newFakeGithub(t)
+	app := &App{GithubClient: client}
+	fsdata := &fsresolutions.FSResolutionData{CsswgResolutionsId: 10}
+	issue := &github.Issue{Number: github.Int(10)}
+	created := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// Both commands parse, but filing the crbug failed, so neither ran.
+	comment := newTestComment(7, "/crbug Blink>Layout\n/defer 2099-01-01", created)
+	var commands []*Command
+	for _, line := range strings.Split(comment.GetBody(), "\n") {
+		command := ParseCommand(line, time.Now())
+		command.Comment = comment
+		commands = append(commands, command)
+	}
+	app.ReportCommands(fsdata, issue, commands, false)
+	if len(fsdata.TriagedCommentIds) != 0 || len(fsdata.FailedComments) != 1 {
+		t.Errorf("TriagedCommentIds is %v and FailedComments %v, want one failed comment",
+			fsdata.TriagedCommentIds, fsdata.FailedComments)
+	}
+
+	edited := created.Add(time.Minute)
+	comment.UpdatedAt = &edited
+	for _, command := range commands {
+		if isHandledCommand(fsdata, command) {
+			t.Errorf("%q is handled after its comment was edited", command.Line)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package triage_task_handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/google/go-github/github"
+)
+
+// Crbug statuses that we report back on the csswg-resolutions issue, mapped to
+// the wording we use in the comment.
+var crbugReportedStatuses = map[string]string{
+	"Fixed":     "fixed",
+	"Verified":  "fixed",
+	"WontFix":   "marked as WontFix",
+	"Duplicate": "marked as a duplicate",
+}
+
+func crbugStatusCommentText(fsdata *fsresolutions.FSResolutionData) string {
+	text := fmt.Sprintf("[crbug.com/%d](https://crbug.com/%d) has been %s",
+		fsdata.CrbugId, fsdata.CrbugId, crbugReportedStatuses[fsdata.CrbugStatus])
+	if fsdata.CrbugMergedInto != 0 {
+		text += fmt.Sprintf(" of [crbug.com/%d](https://crbug.com/%d)",
+			fsdata.CrbugMergedInto, fsdata.CrbugMergedInto)
+	}
+	text += "."
+	if fsdata.CrbugMilestone != "" {
+		text += fmt.Sprintf(" Milestone: M%s.", fsdata.CrbugMilestone)
+	}
+	return text
+}
+
+// Fetches the crbug state from the bug tracker, stores it, and comments on the
+// csswg-resolutions issue if the crbug reached a reportable status.
+func (app *App) SyncCrbug(fsdata *fsresolutions.FSResolutionData) error {
+	issue, err := app.BugTracker.GetIssue("chromium", fsdata.CrbugId)
+	if err != nil {
+		return fmt.Errorf("GetIssue: %v", err)
+	}
+
+	fsdata.CrbugStatus = issue.Status
+	fsdata.CrbugOwner = issue.Owner
+	fsdata.CrbugMilestone = issue.Milestone
+	// Not every tracker reports when the crbug was closed.
+	if !issue.ClosedTime.IsZero() {
+		fsdata.CrbugClosedTime = issue.ClosedTime
+	}
+	fsdata.CrbugMergedInto = issue.MergedInto
+	fsdata.CrbugSyncTime = time.Now()
+
+	_, reportable := crbugReportedStatuses[fsdata.CrbugStatus]
+	if reportable && fsdata.CrbugReportedStatus != fsdata.CrbugStatus {
+		comment_text := crbugStatusCommentText(fsdata)
+		comment := &github.IssueComment{Body: &comment_text}
+		_, _, err := app.GithubClient.Issues.CreateComment(
+			context.Background(), githubLogin, githubRepo, fsdata.CsswgResolutionsId, comment)
+		if err != nil {
+			return fmt.Errorf("Issues.CreateComment: %v", err)
+		}
+		fsdata.CrbugReportedStatus = fsdata.CrbugStatus
+	}
+
+	err = app.FSClient.UpdateDataSetCrbugState(fileNameFromData(fsdata), fsdata)
+	if err != nil {
+		return fmt.Errorf("UpdateDataSetCrbugState: %v", err)
+	}
+	return nil
+}
+
+// Syncs all tracked crbugs. Failures for individual crbugs are logged and
+// don't stop the rest of the sync.
+func (app *App) RunCrbugSync() error {
+	fsdatas, err := app.FSClient.LoadDataWithCrbugs()
+	if err != nil {
+		return fmt.Errorf("LoadDataWithCrbugs: %v", err)
+	}
+
+	githubClient, err := NewGithubClient()
+	if err != nil {
+		return fmt.Errorf("NewGithubClient: %v", err)
+	}
+	app.GithubClient = githubClient
+
+	bugTracker, err := NewBugTracker()
+	if err != nil {
+		return fmt.Errorf("NewBugTracker: %v", err)
+	}
+	app.BugTracker = bugTracker
+
+	failures := 0
+	refs_failures := 0
+	for _, fsdata := range fsdatas {
+		if err := app.SyncCrbug(fsdata); err != nil {
+			log.Printf("ERROR: SyncCrbug crbug %d: %v\n", fsdata.CrbugId, err)
+			failures++
+			continue
+		}
+		if err := app.ReportChromiumRefs(fsdata); err != nil {
+			log.Printf("ERROR: ReportChromiumRefs crbug %d: %v\n", fsdata.CrbugId, err)
+			refs_failures++
+		}
+	}
+	log.Printf("Synced %d crbugs, %d failures, %d chromium refs failures\n",
+		len(fsdatas)-failures, failures, refs_failures)
+
+	if failures != 0 || refs_failures != 0 {
+		return fmt.Errorf("%d of %d crbugs failed to sync, and %d failed to report chromium refs",
+			failures, len(fsdatas), refs_failures)
+	}
+	return nil
+}
+
+// Entry point for the periodic crbug sync job (e.g. from Cloud Scheduler).
+func HandleCrbugSync(w http.ResponseWriter, r *http.Request) {
+	app, err := NewApp()
+	if err != nil {
+		log.Printf("ERROR: NewApp: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer app.FSClient.Close()
+
+	err = app.RunCrbugSync()
+	if err != nil {
+		log.Printf("ERROR: app.RunCrbugSync: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -4,11 +4,16 @@ go 1.19
 
 replace github.com/chromium-helper/csswg-resolutions/monorail => ../../monorail
 
+replace github.com/chromium-helper/csswg-resolutions/fsresolutions => ../../fsresolutions
+
+replace github.com/chromium-helper/csswg-resolutions/suggestions => ../../suggestions
+
 require (
 	cloud.google.com/go/firestore v1.9.0
 	cloud.google.com/go/secretmanager v1.9.0
 	github.com/chromium-helper/csswg-resolutions/fsresolutions v0.1.0
 	github.com/chromium-helper/csswg-resolutions/monorail v0.0.0-00010101000000-000000000000
+	github.com/chromium-helper/csswg-resolutions/suggestions v0.0.0-00010101000000-000000000000
 	github.com/google/go-github v17.0.0+incompatible
 	golang.org/x/oauth2 v0.5.0
 	google.golang.org/api v0.110.0
@@ -20,6 +25,11 @@ require (
 	cloud.google.com/go/compute/metadata v0.2.3 // indirect
 	cloud.google.com/go/iam v0.8.0 // indirect
 	cloud.google.com/go/longrunning v0.3.0 // indirect
+	github.com/chromium-helper/csswg-resolutions/chromiumrefs v0.0.0-00010101000000-000000000000
+	github.com/chromium-helper/csswg-resolutions/listcomments v0.0.0-00010101000000-000000000000
+	github.com/chromium-helper/csswg-resolutions/peertrackers v0.0.0-00010101000000-000000000000
+	github.com/chromium-helper/csswg-resolutions/triagers v0.0.0-00010101000000-000000000000
+	github.com/chromium-helper/csswg-resolutions/wptlinks v0.0.0-00010101000000-000000000000
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/google/go-cmp v0.5.9 // indirect
@@ -38,3 +48,13 @@ require (
 	google.golang.org/grpc v1.53.0 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
 )
+
+replace github.com/chromium-helper/csswg-resolutions/triagers => ../../triagers
+
+replace github.com/chromium-helper/csswg-resolutions/peertrackers => ../../peertrackers
+
+replace github.com/chromium-helper/csswg-resolutions/wptlinks => ../../wptlinks
+
+replace github.com/chromium-helper/csswg-resolutions/chromiumrefs => ../../chromiumrefs
+
+replace github.com/chromium-helper/csswg-resolutions/listcomments => ../../listcomments
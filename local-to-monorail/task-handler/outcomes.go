@@ -0,0 +1,76 @@
+package triage_task_handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/google/go-github/github"
+)
+
+// How long before the issue was closed we look for the closing comment. The
+// "Close with comment" button posts the comment just before closing.
+const closingCommentWindow = 2 * time.Minute
+
+// Records how the issue was triaged. Any outcome but a deferral ends a pending
+// deferral, so that it doesn't reopen the issue or remind anyone later.
+func (app *App) RecordOutcome(fsdata *fsresolutions.FSResolutionData, ghissue *github.Issue, outcome string, decided_by string, reason string) {
+	fsdata.TriageOutcome = outcome
+	fsdata.TriageDecidedBy = decided_by
+	fsdata.TriageDecidedTime = time.Now()
+	fsdata.TriageReason = reason
+	if outcome != fsresolutions.TriageOutcomeDeferred {
+		app.ClearDeferral(fsdata, ghissue)
+	}
+}
+
+// Returns the body of the comment the user closed the issue with, if any.
+func (app *App) closingComment(issue *github.Issue, closed_by string) (string, error) {
+	closed_at := issue.GetClosedAt()
+	since := closed_at.Add(-closingCommentWindow)
+	opts := &github.IssueListCommentsOptions{
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var body string
+	for {
+		comments, resp, err := app.GithubClient.Issues.ListComments(
+			context.Background(), githubLogin, githubRepo, issue.GetNumber(), opts)
+		if err != nil {
+			return "", fmt.Errorf("ListComments: %v", err)
+		}
+		for _, comment := range comments {
+			created_at := comment.GetCreatedAt()
+			if comment.GetUser().GetLogin() == closed_by &&
+				!created_at.Before(since) && !created_at.After(closed_at) {
+				body = comment.GetBody()
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return strings.TrimSpace(body), nil
+}
+
+// Records that a triager closed the issue without filing a crbug, i.e. that
+// no action is needed. The bot records an outcome before it closes an issue,
+// whether for a command, a filed crbug or a triage rule in the poller.
+func (app *App) RecordClosedOutcome(fsdata *fsresolutions.FSResolutionData, issue *github.Issue) error {
+	closed_by := issue.GetClosedBy().GetLogin()
+	if closed_by == githubLogin {
+		return nil
+	}
+
+	reason, err := app.closingComment(issue, closed_by)
+	if err != nil {
+		return fmt.Errorf("closingComment: %v", err)
+	}
+	app.RecordOutcome(fsdata, issue, fsresolutions.TriageOutcomeNoAction, closed_by, reason)
+	fsdata.TriageDecidedTime = issue.GetClosedAt()
+	return nil
+}
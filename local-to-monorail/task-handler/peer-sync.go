@@ -0,0 +1,214 @@
+package triage_task_handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/chromium-helper/csswg-resolutions/listcomments"
+	"github.com/chromium-helper/csswg-resolutions/peertrackers"
+)
+
+// Timeout for searching all peer trackers for one issue.
+const peerSearchTimeout = time.Minute
+
+// Most issues synced per run. The least recently synced go first, so runs
+// cycle through all issues; schedule the job often enough for that.
+const peerSyncBatchSize = 50
+
+func NewPeerTrackers() ([]peertrackers.Tracker, error) {
+	config, err := peertrackers.LoadConfigOrDefault(peerTrackersFile)
+	if err != nil {
+		return nil, fmt.Errorf("peertrackers.LoadConfigOrDefault: %v", err)
+	}
+	return config.NewTrackers(&http.Client{Timeout: peerSearchTimeout}), nil
+}
+
+func toPeerBugs(bugs []*peertrackers.Bug) []*fsresolutions.PeerBug {
+	var peer_bugs []*fsresolutions.PeerBug
+	for _, bug := range bugs {
+		peer_bugs = append(peer_bugs, &fsresolutions.PeerBug{
+			Tracker:    bug.Tracker,
+			Id:         bug.Id,
+			Summary:    bug.Summary,
+			Status:     bug.Status,
+			Resolution: bug.Resolution,
+			URL:        bug.URL,
+		})
+	}
+	return peer_bugs
+}
+
+func fromPeerBugs(peer_bugs []*fsresolutions.PeerBug) []*peertrackers.Bug {
+	var bugs []*peertrackers.Bug
+	for _, bug := range peer_bugs {
+		bugs = append(bugs, &peertrackers.Bug{
+			Tracker:    bug.Tracker,
+			Id:         bug.Id,
+			Summary:    bug.Summary,
+			Status:     bug.Status,
+			Resolution: bug.Resolution,
+			URL:        bug.URL,
+		})
+	}
+	return bugs
+}
+
+func peerBugsEqual(a, b []*fsresolutions.PeerBug) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if *a[i] != *b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Describes the stored bugs for their list comment on the csswg-resolutions
+// issue.
+func peerBugsList(fsdata *fsresolutions.FSResolutionData) (string, string) {
+	what := fmt.Sprintf("bugs in other engines that link to [w3c/csswg-drafts#%d](%s)",
+		fsdata.CsswgDraftsId, peertrackers.DraftsIssueURL(fsdata.CsswgDraftsId))
+	if len(fsdata.PeerBugs) == 0 {
+		return what, ""
+	}
+	return what, peertrackers.Markdown(fromPeerBugs(fsdata.PeerBugs))
+}
+
+// Returns whether peer status still matters for the issue. Issues that needed
+// no action don't need it, nor do issues whose crbug is closed.
+func needsPeerSync(fsdata *fsresolutions.FSResolutionData) bool {
+	switch fsdata.TriageOutcome {
+	case fsresolutions.TriageOutcomeNoAction, fsresolutions.TriageOutcomeDuplicate:
+		return false
+	}
+	if !fsdata.CrbugClosedTime.IsZero() {
+		return false
+	}
+	return fsdata.CsswgDraftsId != 0 && fsdata.CsswgResolutionsId != 0
+}
+
+// Returns the issues that need a peer sync, least recently synced first, at
+// most limit of them.
+func peerSyncBatch(fsdatas []*fsresolutions.FSResolutionData, limit int) []*fsresolutions.FSResolutionData {
+	var batch []*fsresolutions.FSResolutionData
+	for _, fsdata := range fsdatas {
+		if needsPeerSync(fsdata) {
+			batch = append(batch, fsdata)
+		}
+	}
+	sort.SliceStable(batch, func(i, j int) bool {
+		return batch[i].PeerSyncTime.Before(batch[j].PeerSyncTime)
+	})
+	if len(batch) > limit {
+		batch = batch[:limit]
+	}
+	return batch
+}
+
+// Searches the peer trackers for bugs that link to the csswg-drafts issue and
+// stores them on fsdata. When they changed, creates or edits the comment on
+// the csswg-resolutions issue that lists them.
+func (app *App) SyncPeerBugs(fsdata *fsresolutions.FSResolutionData) error {
+	if err := app.updatePeerBugs(fsdata); err != nil {
+		return err
+	}
+	err := app.FSClient.UpdateDataSetPeerBugs(fileNameFromData(fsdata), fsdata)
+	if err != nil {
+		return fmt.Errorf("UpdateDataSetPeerBugs: %v", err)
+	}
+	return nil
+}
+
+// SyncPeerBugs without storing fsdata.
+func (app *App) updatePeerBugs(fsdata *fsresolutions.FSResolutionData) error {
+	ctx, cancel := context.WithTimeout(context.Background(), peerSearchTimeout)
+	defer cancel()
+	bugs, err := peertrackers.SearchAll(ctx, app.PeerTrackers, fsdata.CsswgDraftsId)
+	if err != nil {
+		// A partial result would look like bugs went away.
+		return err
+	}
+
+	peer_bugs := toPeerBugs(bugs)
+	changed := !peerBugsEqual(peer_bugs, fsdata.PeerBugs)
+	fsdata.PeerBugs = peer_bugs
+	fsdata.PeerSyncTime = time.Now()
+
+	if changed {
+		what, list := peerBugsList(fsdata)
+		fsdata.PeerBugsCommentId, err = listcomments.Update(context.Background(), app.GithubClient,
+			githubLogin, githubRepo, fsdata.CsswgResolutionsId, fsdata.PeerBugsCommentId,
+			what, list, fsdata.PeerSyncTime)
+		if err != nil {
+			return fmt.Errorf("listcomments.Update: %v", err)
+		}
+		log.Printf("Updated peer bugs on issue #%d: %d bugs\n",
+			fsdata.CsswgResolutionsId, len(peer_bugs))
+	}
+	return nil
+}
+
+// Syncs peer bugs for the next batch of issues where they matter. Failures for
+// individual issues are logged and don't stop the rest of the sync.
+func (app *App) RunPeerSync() error {
+	fsdatas, err := app.FSClient.LoadAllData()
+	if err != nil {
+		return fmt.Errorf("LoadAllData: %v", err)
+	}
+
+	githubClient, err := NewGithubClient()
+	if err != nil {
+		return fmt.Errorf("NewGithubClient: %v", err)
+	}
+	app.GithubClient = githubClient
+
+	peerTrackers, err := NewPeerTrackers()
+	if err != nil {
+		return fmt.Errorf("NewPeerTrackers: %v", err)
+	}
+	app.PeerTrackers = peerTrackers
+
+	synced := 0
+	failures := 0
+	for _, fsdata := range peerSyncBatch(fsdatas, peerSyncBatchSize) {
+		if err := app.SyncPeerBugs(fsdata); err != nil {
+			log.Printf("ERROR: SyncPeerBugs issue #%d: %v\n", fsdata.CsswgResolutionsId, err)
+			failures++
+			continue
+		}
+		synced++
+	}
+	log.Printf("Synced peer bugs for %d issues, %d failures\n", synced, failures)
+
+	if failures != 0 {
+		return fmt.Errorf("%d of %d issues failed to sync peer bugs", failures, synced+failures)
+	}
+	return nil
+}
+
+// Entry point for the periodic peer tracker sync job (e.g. from Cloud
+// Scheduler).
+func HandlePeerSync(w http.ResponseWriter, r *http.Request) {
+	app, err := NewApp()
+	if err != nil {
+		log.Printf("ERROR: NewApp: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer app.FSClient.Close()
+
+	err = app.RunPeerSync()
+	if err != nil {
+		log.Printf("ERROR: app.RunPeerSync: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,178 @@
+package triage_task_handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/chromium-helper/csswg-resolutions/peertrackers"
+	"github.com/google/go-github/github"
+)
+
+// A GitHub server that records comment creates and edits.
+type fakeGithub struct {
+	server *httptest.Server
+	mutex  sync.Mutex
+	// "POST <path>" or "PATCH <path>", with the comment body
+	requests []string
+	bodies   []string
+}
+
+func newFakeGithub(t *testing.T) (*fakeGithub, *github.Client) {
+	fake := &fakeGithub{}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var comment github.IssueComment
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		}
+		fake.mutex.Lock()
+		fake.requests = append(fake.requests, r.Method+" "+r.URL.Path)
+		fake.bodies = append(fake.bodies, comment.GetBody())
+		fake.mutex.Unlock()
+
+		id := int64(42)
+		comment.ID = &id
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&comment)
+	}))
+	t.Cleanup(fake.server.Close)
+
+	client := github.NewClient(nil)
+	base_url, err := url.Parse(fake.server.URL + "/")
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	client.BaseURL = base_url
+	return fake, client
+}
+
+func newPeerSyncTest(t *testing.T, bugs ...*peertrackers.FakeBugzillaBug) (*App, *fakeGithub, *peertrackers.FakeBugzilla) {
+	githubLogin = "w3c"
+	githubRepo = "csswg-resolutions"
+	bugzilla := peertrackers.NewFakeBugzilla(bugs...)
+	t.Cleanup(bugzilla.Close)
+	fake_github, client := newFakeGithub(t)
+	app := &App{
+		GithubClient: client,
+		PeerTrackers: []peertrackers.Tracker{peertrackers.NewBugzilla("Gecko", bugzilla.URL, nil)},
+	}
+	return app, fake_github, bugzilla
+}
+
+func TestUpdatePeerBugsCreatesComment(t *testing.T) {
+	app, fake_github, _ := newPeerSyncTest(t, &peertrackers.FakeBugzillaBug{
+		Id: 1, Summary: "Implement it", Status: "NEW", URL: peertrackers.DraftsIssueURL(5)})
+	fsdata := &fsresolutions.FSResolutionData{CsswgDraftsId: 5, CsswgResolutionsId: 10}
+
+	if err := app.updatePeerBugs(fsdata); err != nil {
+		t.Fatalf("updatePeerBugs: %v", err)
+	}
+	if want := "POST /repos/w3c/csswg-resolutions/issues/10/comments"; strings.Join(fake_github.requests, ",") != want {
+		t.Errorf("requests are %v, want %s", fake_github.requests, want)
+	}
+	if len(fake_github.bodies) == 1 && !strings.Contains(fake_github.bodies[0], "Implement it") {
+		t.Errorf("comment %q doesn't list the bug", fake_github.bodies[0])
+	}
+	if fsdata.PeerBugsCommentId != 42 {
+		t.Errorf("PeerBugsCommentId is %d, want 42", fsdata.PeerBugsCommentId)
+	}
+	if len(fsdata.PeerBugs) != 1 || fsdata.PeerBugs[0].Id != 1 {
+		t.Errorf("PeerBugs are %+v, want bug 1", fsdata.PeerBugs)
+	}
+}
+
+func TestUpdatePeerBugsEditsComment(t *testing.T) {
+	app, fake_github, bugzilla := newPeerSyncTest(t)
+	bugzilla.AddBug(&peertrackers.FakeBugzillaBug{
+		Id: 1, Status: "RESOLVED", Resolution: "FIXED", URL: peertrackers.DraftsIssueURL(5)})
+	fsdata := &fsresolutions.FSResolutionData{
+		CsswgDraftsId: 5, CsswgResolutionsId: 10, PeerBugsCommentId: 42,
+		PeerBugs: []*fsresolutions.PeerBug{{Tracker: "Gecko", Id: 1, Status: "NEW",
+			URL: bugzilla.URL + "/show_bug.cgi?id=1"}},
+	}
+
+	if err := app.updatePeerBugs(fsdata); err != nil {
+		t.Fatalf("updatePeerBugs: %v", err)
+	}
+	if want := "PATCH /repos/w3c/csswg-resolutions/issues/comments/42"; strings.Join(fake_github.requests, ",") != want {
+		t.Errorf("requests are %v, want %s", fake_github.requests, want)
+	}
+	if fsdata.PeerBugs[0].Resolution != "FIXED" {
+		t.Errorf("stored resolution is %q, want FIXED", fsdata.PeerBugs[0].Resolution)
+	}
+}
+
+func TestUpdatePeerBugsUnchanged(t *testing.T) {
+	app, fake_github, bugzilla := newPeerSyncTest(t)
+	bugzilla.AddBug(&peertrackers.FakeBugzillaBug{Id: 1, Status: "NEW", URL: peertrackers.DraftsIssueURL(5)})
+	fsdata := &fsresolutions.FSResolutionData{
+		CsswgDraftsId: 5, CsswgResolutionsId: 10, PeerBugsCommentId: 42,
+		PeerBugs: []*fsresolutions.PeerBug{{Tracker: "Gecko", Id: 1, Status: "NEW",
+			URL: bugzilla.URL + "/show_bug.cgi?id=1"}},
+	}
+
+	if err := app.updatePeerBugs(fsdata); err != nil {
+		t.Fatalf("updatePeerBugs: %v", err)
+	}
+	if len(fake_github.requests) != 0 {
+		t.Errorf("requests are %v, want none", fake_github.requests)
+	}
+	if fsdata.PeerSyncTime.IsZero() {
+		t.Errorf("PeerSyncTime wasn't set")
+	}
+}
+
+func TestUpdatePeerBugsNothingFound(t *testing.T) {
+	app, fake_github, _ := newPeerSyncTest(t)
+	fsdata := &fsresolutions.FSResolutionData{CsswgDraftsId: 5, CsswgResolutionsId: 10}
+
+	if err := app.updatePeerBugs(fsdata); err != nil {
+		t.Fatalf("updatePeerBugs: %v", err)
+	}
+	if len(fake_github.requests) != 0 {
+		t.Errorf("requests are %v, want none", fake_github.requests)
+	}
+}
+
+func TestUpdatePeerBugsSearchFailure(t *testing.T) {
+	app, fake_github, bugzilla := newPeerSyncTest(t)
+	bugzilla.SetFailure("overloaded")
+	stored := []*fsresolutions.PeerBug{{Tracker: "Gecko", Id: 1, Status: "NEW"}}
+	fsdata := &fsresolutions.FSResolutionData{
+		CsswgDraftsId: 5, CsswgResolutionsId: 10, PeerBugsCommentId: 42, PeerBugs: stored}
+
+	if err := app.updatePeerBugs(fsdata); err == nil {
+		t.Errorf("updatePeerBugs succeeded, want the search failure")
+	}
+	if len(fake_github.requests) != 0 || len(fsdata.PeerBugs) != 1 {
+		t.Errorf("a failed search changed the peer bugs")
+	}
+}
+
+func TestPeerSyncBatch(t *testing.T) {
+	now := time.Now()
+	var fsdatas []*fsresolutions.FSResolutionData
+	for i := 1; i <= 5; i++ {
+		fsdatas = append(fsdatas, &fsresolutions.FSResolutionData{
+			CsswgDraftsId: i, CsswgResolutionsId: 100 + i,
+			PeerSyncTime: now.Add(-time.Duration(i) * time.Hour),
+		})
+	}
+	fsdatas[4].TriageOutcome = fsresolutions.TriageOutcomeNoAction
+	fsdatas[3].CrbugClosedTime = now
+
+	var got []string
+	for _, fsdata := range peerSyncBatch(fsdatas, 2) {
+		got = append(got, fmt.Sprint(fsdata.CsswgDraftsId))
+	}
+	if want := "3,2"; strings.Join(got, ",") != want {
+		t.Errorf("batch is %v, want %s", got, want)
+	}
+}
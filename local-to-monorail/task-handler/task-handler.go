@@ -2,8 +2,10 @@ package triage_task_handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/idtoken"
 	"log"
 	"net/http"
@@ -11,11 +13,16 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	gcpsm "cloud.google.com/go/secretmanager/apiv1"
 	gcpsmpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
 	"github.com/chromium-helper/csswg-resolutions/monorail"
+	"github.com/chromium-helper/csswg-resolutions/peertrackers"
+	"github.com/chromium-helper/csswg-resolutions/suggestions"
+	"github.com/chromium-helper/csswg-resolutions/triagers"
+	"github.com/chromium-helper/csswg-resolutions/wptlinks"
 	"github.com/google/go-github/github"
 )
 
@@ -27,11 +34,34 @@ var (
 	githubRepo            = os.Getenv("GITHUB_REPO")
 	componentLabelPrefix  = os.Getenv("COMPONENT_LABEL_PREFIX")
 	metaBugLabel          = os.Getenv("META_BUG_LABEL")
+	// Either "monorail" (default) or "issuetracker"
+	bugTracker = os.Getenv("BUG_TRACKER")
+	// Json file mapping component names to issue tracker component ids
+	issueTrackerComponentIdsFile = os.Getenv("ISSUE_TRACKER_COMPONENT_IDS_FILE")
+	// (optional) How long to cache collaborator permissions
+	collaboratorCacheTTL = os.Getenv("COLLABORATOR_CACHE_TTL_SECONDS")
+	// (optional) Json file listing who may triage; see triagers.Config
+	triagersFile = os.Getenv("TRIAGERS_FILE")
+	// (optional) Label for issues whose triage is deferred, "deferred" by default
+	deferredLabelName = os.Getenv("DEFERRED_LABEL")
+	// (optional) Json file listing the peer trackers to search; see
+	// peertrackers.Config
+	peerTrackersFile = os.Getenv("PEER_TRACKERS_FILE")
+)
+
+// Directives that accept the component, and the owner and ccs suggested by the
+// poller. Keep these in sync with csswg-to-local-cf.
+const (
+	acceptDirective       = "accept"
+	acceptOwnersDirective = "accept-owners"
 )
 
 type App struct {
 	FSClient     *fsresolutions.Client
 	GithubClient *github.Client
+	BugTracker   monorail.BugTracker
+	Triagers     *triagers.Checker
+	PeerTrackers []peertrackers.Tracker
 }
 
 type Directive struct {
@@ -41,6 +71,25 @@ type Directive struct {
 	CcList []string
 	Commenter string
 	Comment string
+	// True if a triager accepted the suggested component
+	AcceptSuggestion bool
+	// True if a triager accepted the suggested owner and ccs
+	AcceptOwnersSuggestion bool
+	// Crbug priority from a /crbug command, or empty for the default
+	Priority string
+	// Github login of the triager whose label, directive or command decides
+	// the outcome
+	DecidedBy string
+
+	// All slash commands from triagers, in order
+	Commands []*Command
+	// Name and command of the last valid slash command, which decides what
+	// happens to the issue
+	Action string
+	ActionCommand *Command
+	CloseReason string
+	DuplicateOf int
+	DeferUntil time.Time
 }
 
 func NewApp() (*App, error) {
@@ -92,31 +141,83 @@ func fileNameFromData(fsdata *fsresolutions.FSResolutionData) string {
 	return fmt.Sprintf("%d", fsdata.CsswgDraftsId)
 }
 
-func (app *App) UpdateMonorailIssue(ghissue *github.Issue, directive *Directive) (*monorail.Issue, error) {
+// Creates the bug tracker selected by the BUG_TRACKER environment variable.
+func NewBugTracker() (monorail.BugTracker, error) {
+	switch bugTracker {
+	case "", "monorail":
+		return NewMonorailService()
+	case "issuetracker":
+		return NewIssueTrackerService()
+	}
+	return nil, fmt.Errorf("unknown BUG_TRACKER %s", bugTracker)
+}
+
+func NewIssueTrackerService() (*monorail.IssueTrackerService, error) {
+	component_ids, err := monorail.LoadComponentIds(issueTrackerComponentIdsFile)
+	if err != nil {
+		return nil, fmt.Errorf("LoadComponentIds: %v", err)
+	}
+
+	ctx := context.Background()
+	new_token_source := func() (oauth2.TokenSource, error) {
+		return google.DefaultTokenSource(ctx, monorail.IssueTrackerScope)
+	}
+
+	service, err := monorail.NewIssueTrackerService(ctx, new_token_source, component_ids)
+	if err != nil {
+		return nil, fmt.Errorf("monorail.NewIssueTrackerService: %v", err)
+	}
+	return service, nil
+}
+
+func NewMonorailService() (*monorail.IssuesService, error) {
 	audience, err := monorail.GetAudience("prod")
 	if err != nil {
 		return nil, fmt.Errorf("GetAudience: %v", err)
 	}
 
 	ctx := context.Background()
-	token_source, err := idtoken.NewTokenSource(ctx, audience)
-	if err != nil {
-		return nil, fmt.Errorf("NewTokenSource: %v", err)
+	new_token_source := func() (oauth2.TokenSource, error) {
+		return idtoken.NewTokenSource(ctx, audience)
 	}
 
-	service, err := monorail.NewIssuesService(ctx, "prod", token_source)
+	service, err := monorail.NewIssuesService(ctx, "prod", new_token_source)
 	if err != nil {
 		return nil, fmt.Errorf("monorail.NewIssuesService: %v", err)
 	}
+	return service, nil
+}
+
+// Most tests listed in the crbug description; wpt.fyi has the rest.
+const maxWptTestsInDescription = 20
+
+func wptTestsText(tests []string) string {
+	if len(tests) == 0 {
+		return ""
+	}
+	text := "Related web-platform-tests:\n"
+	for i, test := range tests {
+		if i == maxWptTestsInDescription {
+			text += fmt.Sprintf("...and %d more\n", len(tests)-i)
+			break
+		}
+		text += fmt.Sprintf("  %s\n", wptlinks.ResultsURL(test))
+	}
+	return text + "\n"
+}
 
+func (app *App) UpdateMonorailIssue(fsdata *fsresolutions.FSResolutionData, ghissue *github.Issue, directive *Directive) (*monorail.Issue, error) {
 	description := ghissue.GetBody()
 	description += "\n\n"
+	description += wptTestsText(fsdata.WptTests)
+	description += chromiumRefsText(fsdata.ChromiumRefs)
 	if directive.Comment != "" {
 		description += fmt.Sprintf("%s left an additional comment:\n%s\n\n", directive.Commenter, directive.Comment)
 	}
 	description += fmt.Sprintf("This issue has been triaged via https://github.com/chromium-helper/csswg-resolutions/issues/%d\n", ghissue.GetNumber())
 
 	var issue *monorail.Issue
+	var err error
 	if directive.Crbug == 0 {
 		description += "If no action is needed, feel free to close this bug. Otherwise, please prioritize the work needed for the above resolutions."
 		description += "\n\n"
@@ -128,10 +229,11 @@ func (app *App) UpdateMonorailIssue(ghissue *github.Issue, directive *Directive)
 			Components:  directive.Components,
 			Owner:			 directive.Owner,
 			CcList:      directive.CcList,
+			Priority:    directive.Priority,
 		}
-		issue, err = service.CreateIssue(request)
+		issue, err = app.BugTracker.CreateIssue(request)
 		if err != nil {
-			return nil, fmt.Errorf("monorail.CreateIssue: %v", err)
+			return nil, fmt.Errorf("monorail.CreateIssue: %w", err)
 		}
 	} else {
 		request := &monorail.ModifyIssueRequest{
@@ -140,9 +242,9 @@ func (app *App) UpdateMonorailIssue(ghissue *github.Issue, directive *Directive)
 			Comment:		description,
 			// TODO(vmpstr): Once we get permission, add owners/cc/components
 		}
-		err = service.ModifyIssue(request)
+		err = app.BugTracker.ModifyIssue(request)
 		if err != nil {
-			return nil, fmt.Errorf("monorail.ModifyIssue: %v", err)
+			return nil, fmt.Errorf("monorail.ModifyIssue: %w", err)
 		}
 		issue = &monorail.Issue{ Id: directive.Crbug }
 	}
@@ -150,11 +252,15 @@ func (app *App) UpdateMonorailIssue(ghissue *github.Issue, directive *Directive)
 }
 
 func (app *App) CommentAndClose(action string, ghissue *github.Issue, crbug_id int) error {
+	comment_text := fmt.Sprintf("I have %s [crbug.com/%d](https://crbug.com/%d)\n\n", action, crbug_id, crbug_id)
+	comment_text += "That is all that can be done here, closing issue."
+	return app.CommentAndCloseWithText(ghissue, comment_text)
+}
+
+func (app *App) CommentAndCloseWithText(ghissue *github.Issue, comment_text string) error {
 	ctx := context.Background()
 
 	// Add a comment
-	comment_text := fmt.Sprintf("I have %s [crbug.com/%d](https://crbug.com/%d)\n\n", action, crbug_id, crbug_id)
-	comment_text += "That is all that can be done here, closing issue."
 	comment := &github.IssueComment{Body: &comment_text}
 	_, _, err := app.GithubClient.Issues.CreateComment(
 		ctx, githubLogin, githubRepo, ghissue.GetNumber(), comment)
@@ -173,6 +279,31 @@ func (app *App) CommentAndClose(action string, ghissue *github.Issue, crbug_id i
 	return nil
 }
 
+// Lets the triager know that the bug tracker rejected the directive, so that
+// they can fix it up. The issue is left open.
+func (app *App) CommentTriageError(ghissue *github.Issue, api_err *monorail.Error) error {
+	var reason string
+	switch api_err.Code {
+	case monorail.CodeInvalidArgument:
+		reason = "Please check that the `crbug:` component labels, owner and cc list are valid."
+	case monorail.CodeNotFound:
+		reason = "Please check that the crbug and any users mentioned exist."
+	case monorail.CodePermissionDenied:
+		reason = "I don't have permission to do that; a human will need to file or update the crbug."
+	default:
+		return nil
+	}
+
+	comment_text := fmt.Sprintf("I could not file or update a crbug, because the bug tracker said:\n> %s\n\n%s", api_err.Message, reason)
+	comment := &github.IssueComment{Body: &comment_text}
+	_, _, err := app.GithubClient.Issues.CreateComment(
+		context.Background(), githubLogin, githubRepo, ghissue.GetNumber(), comment)
+	if err != nil {
+		return fmt.Errorf("Issues.CreateComment: %v", err)
+	}
+	return nil
+}
+
 func ParseComponents(issue *github.Issue) ([]string, bool) {
 	var components []string
 	for _, label := range issue.Labels {
@@ -188,7 +319,7 @@ func ParseComponents(issue *github.Issue) ([]string, bool) {
 	return components, false
 }
 
-func (app *App) ParseDirective(issue *github.Issue) (*Directive, bool, error) {
+func (app *App) ParseDirective(fsdata *fsresolutions.FSResolutionData, issue *github.Issue) (*Directive, bool, error) {
 	var directive Directive
 	var skip bool
 	directive.Components, skip = ParseComponents(issue)
@@ -196,55 +327,95 @@ func (app *App) ParseDirective(issue *github.Issue) (*Directive, bool, error) {
 		return nil, true, nil
 	}
 
-	ctx := context.Background()
-	collaborators, _, err := app.GithubClient.Repositories.ListCollaborators(ctx, githubLogin, githubRepo, nil)
+	components, labeler, err := app.FilterUnauthorizedLabels(issue, directive.Components)
 	if err != nil {
-		return nil, false, fmt.Errorf("ListCollaborators: %v")
+		return nil, false, fmt.Errorf("FilterUnauthorizedLabels: %v", err)
 	}
+	directive.Components = components
+	directive.DecidedBy = labeler
 
-	collaborator_set := make(map[string]bool)
-	for _, collaborator := range collaborators {
-		collaborator_set[collaborator.GetLogin()] = true
-	}
+	ctx := context.Background()
 
-	get_user := func(input string) string {
-		user := strings.Trim(input, " \n\r")
-		if !strings.Contains(user, "@") {
-			user += "@chromium.org"
+	var comments []*github.IssueComment
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := app.GithubClient.Issues.ListComments(ctx, githubLogin, githubRepo, issue.GetNumber(), opts)
+		if err != nil {
+			return nil, false, fmt.Errorf("ListComments: %v", err)
 		}
-		return user
-	}
-
-	comments, _, err := app.GithubClient.Issues.ListComments(ctx, githubLogin, githubRepo, issue.GetNumber(), nil)
-	if err != nil {
-		return nil, false, fmt.Errorf("ListComments: %v")
+		comments = append(comments, page...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
+	labels := labelNames(issue)
 	for _, comment := range comments {
-		if !collaborator_set[comment.GetUser().GetLogin()] {
+		login := comment.GetUser().GetLogin()
+		is_triager, err := app.Triagers.IsTriagerFor(login, labels)
+		if err != nil {
+			return nil, false, fmt.Errorf("IsTriagerFor: %v", err)
+		}
+		if !is_triager {
 			continue
 		}
 
 		lines := strings.Split(comment.GetBody(), "\n")
 		for _, line := range lines {
+			if command := ParseCommand(line, time.Now()); command != nil {
+				command.Comment = comment
+				// Commands only run once. Replaying an old /close or /dup
+				// would close the issue again after someone reopened it.
+				if isHandledCommand(fsdata, command) {
+					directive.Commands = append(directive.Commands, command)
+					continue
+				}
+				for _, name := range command.DirectiveNames() {
+					allowed, err := app.Triagers.MayUseDirective(login, name, labels)
+					if err != nil {
+						return nil, false, fmt.Errorf("MayUseDirective: %v", err)
+					}
+					if !allowed && command.Err == nil {
+						command.Err = fmt.Errorf("you may not use `%s` on this issue", name)
+					}
+				}
+				directive.ApplyCommand(command)
+				continue
+			}
+
 			lower_line := strings.ToLower(line);
+			allowed, err := app.Triagers.MayUseDirective(login, directiveName(lower_line), labels)
+			if err != nil {
+				return nil, false, fmt.Errorf("MayUseDirective: %v", err)
+			}
+			if !allowed {
+				log.Printf("Ignoring '%s' from %s on #%d\n", line, login, issue.GetNumber())
+				continue
+			}
 			if strings.HasPrefix(lower_line, "crbug:") || strings.HasPrefix(lower_line, "bug:") {
 				re := regexp.MustCompile(`[0-9]{5,}`)
+				directive.DecidedBy = login
 				directive.Crbug, err = strconv.Atoi(re.FindString(line))
 				if err != nil {
 					fmt.Printf("WARNING: Could not parse crbug from '%s'\n", line)
 					directive.Crbug = 0
 				}
 			} else if strings.HasPrefix(lower_line, "owner:") {
-				directive.Owner = get_user(line[len("owner:"):])
+				directive.Owner = userEmail(line[len("owner:"):])
 			} else if strings.HasPrefix(lower_line, "cc:") {
 				parts := strings.Split(line[len("cc:"):], ",")
 				for _, part := range parts {
-					directive.CcList = append(directive.CcList, get_user(strings.Trim(part, " \n\r")))
+					directive.CcList = append(directive.CcList, userEmail(part))
 				}
 			} else if strings.HasPrefix(lower_line, "comment:") {
 				directive.Comment = strings.Trim(line[len("comment:"):], " \n\r")
 				directive.Commenter = comment.GetUser().GetLogin();
+			} else if strings.TrimSpace(lower_line) == acceptDirective {
+				directive.AcceptSuggestion = true
+				directive.DecidedBy = login
+			} else if strings.TrimSpace(lower_line) == acceptOwnersDirective {
+				directive.AcceptOwnersSuggestion = true
 			}
 		}
 	}
@@ -252,8 +423,10 @@ func (app *App) ParseDirective(issue *github.Issue) (*Directive, bool, error) {
 }
 
 func (app *App) ProcessIssue(fsdata *fsresolutions.FSResolutionData) error {
+	deferral_due := !fsdata.DeferredUntil.IsZero() && !fsdata.DeferredUntil.After(time.Now())
+
 	// We already have a bug filed (TODO: maybe we need to add a comment?)
-	if fsdata.CrbugId != 0 {
+	if fsdata.CrbugId != 0 && !deferral_due {
 		return nil
 	}
 
@@ -263,65 +436,203 @@ func (app *App) ProcessIssue(fsdata *fsresolutions.FSResolutionData) error {
 		return fmt.Errorf("Issues.Get: %v", err)
 	}
 
-	// Issue is closed, don't process it.
+	// The crbug was filed while the issue was deferred, so the deferral is moot.
+	if fsdata.CrbugId != 0 {
+		app.ClearDeferral(fsdata, issue)
+		return nil
+	}
+
+	if deferral_due {
+		issue, err = app.EndDeferral(fsdata, issue)
+		if err != nil {
+			return fmt.Errorf("EndDeferral: %v", err)
+		}
+	}
+
+	// Issue is closed, so there is nothing to triage. Just note why.
 	if issue.GetState() == "closed" {
+		return app.RecordClosedOutcome(fsdata, issue)
+	}
+
+	directive, skip, err := app.ParseDirective(fsdata, issue)
+	if err != nil {
+		return fmt.Errorf("ParseDirectives: %v", err)
+	}
+
+	if skip {
 		return nil
 	}
 
-	directive, skip, err := app.ParseDirective(issue)
+	// Acknowledge new commands, and report how they went once we're done.
+	commands := newCommands(fsdata, directive.Commands)
+	for _, command := range commands {
+		app.reactToCommand(command, reactionSeen)
+	}
+	ok, err := app.TriageIssue(fsdata, issue, directive)
 	if err != nil {
-		return fmt.Errorf("ParseDirectives: %v")
+		ok = false
+	}
+	app.ReportCommands(fsdata, issue, commands, ok)
+	return err
+}
+
+// Files or updates the crbug, or carries out the slash command. Returns false
+// if the directive had problems that were reported on the issue.
+func (app *App) TriageIssue(fsdata *fsresolutions.FSResolutionData, issue *github.Issue, directive *Directive) (bool, error) {
+	if directive.Action != "" && directive.Action != crbugCommand {
+		return true, app.RunAction(fsdata, issue, directive)
+	}
+
+	// Explicit component labels and crbugs take precedence over suggestions.
+	if directive.AcceptSuggestion && len(directive.Components) == 0 &&
+		directive.Crbug == 0 && len(fsdata.SuggestedComponents) != 0 {
+		directive.Components = fsdata.SuggestedComponents[:1]
+	}
+	if directive.AcceptOwnersSuggestion {
+		if directive.Owner == "" {
+			directive.Owner = fsdata.SuggestedOwner
+		}
+		if len(directive.CcList) == 0 {
+			directive.CcList = fsdata.SuggestedCcList
+		}
 	}
 
 	// We need a component or a crbug
-	if skip || (len(directive.Components) == 0 && directive.Crbug == 0) {
-		return nil
+	if len(directive.Components) == 0 && directive.Crbug == 0 {
+		return true, nil
 	}
 
-	crbug, err := app.UpdateMonorailIssue(issue, directive)
+	problems, err := app.ValidateDirective(directive)
 	if err != nil {
-		return fmt.Errorf("UpdateMonorailIssue: %v", err)
+		return false, fmt.Errorf("ValidateDirective: %v", err)
+	}
+	if len(problems) != 0 {
+		return false, app.CommentDirectiveProblems(issue, problems)
+	}
+
+	crbug, err := app.UpdateMonorailIssue(fsdata, issue, directive)
+	if err != nil {
+		var api_err *monorail.Error
+		if errors.As(err, &api_err) {
+			if comment_err := app.CommentTriageError(issue, api_err); comment_err != nil {
+				log.Printf("ERROR: CommentTriageError: %v\n", comment_err)
+			}
+		}
+		return false, fmt.Errorf("UpdateMonorailIssue: %v", err)
 	}
 
 	var action string
 	if directive.Crbug == 0 {
 		action = "filed"
+		app.RecordTriageChoices(issue, directive)
+		app.RecordOutcome(fsdata, issue, fsresolutions.TriageOutcomeCrbugFiled, directive.DecidedBy, "")
 	} else {
 		action = "updated"
+		app.RecordOutcome(fsdata, issue, fsresolutions.TriageOutcomeCrbugUpdated, directive.DecidedBy, "")
 	}
 
 	fsdata.CrbugId = crbug.Id
+	if len(fsdata.ChromiumRefs) != 0 {
+		// The description or comment listed them.
+		fsdata.ChromiumRefsCrbugTime = time.Now()
+	}
 	err = app.CommentAndClose(action, issue, crbug.Id)
 	if err != nil {
-		return fmt.Errorf("CommentAndClose: %v", err)
+		return false, fmt.Errorf("CommentAndClose: %v", err)
 	}
-	return nil
+	return true, nil
 }
 
-func (app *App) UpdateFsDataAndClose(fsdata *fsresolutions.FSResolutionData) {
-	err := app.FSClient.SetData(fileNameFromData(fsdata), fsdata)
-	// TODO: Rework this to return the value instead of panicking.
-	if err != nil {
-		panic(err)
+// Records the components, owner and ccs picked for this issue's specs, so that
+// the poller can suggest them for future issues. Failures are only logged,
+// since the crbug has already been filed at this point.
+func (app *App) RecordTriageChoices(issue *github.Issue, directive *Directive) {
+	specs := suggestions.SpecNames(labelNames(issue))
+	if len(specs) == 0 {
+		return
+	}
+	if len(directive.Components) != 0 {
+		if err := app.FSClient.RecordComponentChoices(specs, directive.Components); err != nil {
+			log.Printf("ERROR: RecordComponentChoices: %v\n", err)
+		}
+	}
+	if directive.Owner != "" {
+		if err := app.FSClient.RecordOwnerChoices(specs, []string{directive.Owner}); err != nil {
+			log.Printf("ERROR: RecordOwnerChoices: %v\n", err)
+		}
+	}
+	if len(directive.CcList) != 0 {
+		if err := app.FSClient.RecordCcChoices(specs, directive.CcList); err != nil {
+			log.Printf("ERROR: RecordCcChoices: %v\n", err)
+		}
 	}
-	app.FSClient.Close()
 }
 
-func (app *App) Run(csswg_resolutions_id int) error {
+// Returns true if a newer task was scheduled for the same issue. task_name is
+// the short task name from the X-CloudTasks-TaskName header.
+func IsSupersededTask(fsdata *fsresolutions.FSResolutionData, task_name string) bool {
+	if task_name == "" || fsdata.PendingTaskName == "" {
+		return false
+	}
+	return !strings.HasSuffix(fsdata.PendingTaskName, "/tasks/"+task_name)
+}
+
+func (app *App) Run(csswg_resolutions_id int, task_name string) error {
+	defer app.FSClient.Close()
 	fsdata, err := app.FSClient.LoadDataByCsswgResolutionsId(csswg_resolutions_id)
 	if err != nil {
 		return fmt.Errorf("LoadDataByCsswgResolutionsId: %v", err)
 	}
+
+	// The newer task will process the issue, with all the events so far.
+	if IsSupersededTask(fsdata, task_name) {
+		log.Printf("Task %s was superseded by %s\n", task_name, fsdata.PendingTaskName)
+		return nil
+	}
+
+	// Clear the pending task now, so that events that arrive while we run
+	// schedule a new one.
 	fsdata.HasPendingTriageEvents = false
-	defer app.UpdateFsDataAndClose(fsdata)
+	fsdata.PendingTaskName = ""
+	fsdata.PendingSince = time.Time{}
+	fsdata.PendingUpdateTime = time.Time{}
+	err = app.FSClient.UpdateDataSetPendingTask(fileNameFromData(fsdata), fsdata)
+	if err != nil {
+		return fmt.Errorf("UpdateDataSetPendingTask: %v", err)
+	}
 
 	githubClient, err := NewGithubClient()
 	if err != nil {
 		return fmt.Errorf("NewGithubClient: %v", err)
 	}
 	app.GithubClient = githubClient
+
+	app.Triagers, err = NewTriagerChecker(app.GithubClient, app.FSClient)
+	if err != nil {
+		return fmt.Errorf("NewTriagerChecker: %v", err)
+	}
+
+	bugTracker, err := NewBugTracker()
+	if err != nil {
+		return fmt.Errorf("NewBugTracker: %v", err)
+	}
+	app.BugTracker = bugTracker
 	err = app.ProcessIssue(fsdata)
-	return err
+
+	// Record how far triage got, even if it failed part way. The other fields
+	// belong to the webhook, the syncs and the cli, which may have changed
+	// them since we loaded the data.
+	update_err := app.FSClient.UpdateDataSetTriage(fileNameFromData(fsdata), fsdata)
+	if err != nil {
+		if update_err != nil {
+			log.Printf("ERROR: UpdateDataSetTriage: %v\n", update_err)
+		}
+		return fmt.Errorf("ProcessIssue: %v", err)
+	}
+	if update_err != nil {
+		return fmt.Errorf("UpdateDataSetTriage: %v", update_err)
+	}
+	return nil
 }
 
 func HandleQueueTask(w http.ResponseWriter, r *http.Request) {
@@ -347,7 +658,7 @@ func HandleQueueTask(w http.ResponseWriter, r *http.Request) {
 		log.Printf("ERROR: NewApp: %v\n", err)
 		return
 	}
-	err = app.Run(csswg_resolutions_id)
+	err = app.Run(csswg_resolutions_id, r.Header.Get("X-CloudTasks-TaskName"))
 	if err != nil {
 		log.Printf("ERROR: app.Run: %v\n", err)
 		return
@@ -0,0 +1,152 @@
+package triage_task_handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/chromium-helper/csswg-resolutions/triagers"
+	"github.com/google/go-github/github"
+)
+
+func NewTriagerChecker(github_client *github.Client, fsclient *fsresolutions.Client) (*triagers.Checker, error) {
+	config, err := triagers.LoadConfigOrDefault(triagersFile)
+	if err != nil {
+		return nil, fmt.Errorf("LoadConfigOrDefault: %v", err)
+	}
+
+	ttl := triagers.DefaultCacheTTL
+	if collaboratorCacheTTL != "" {
+		seconds, err := strconv.Atoi(collaboratorCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("COLLABORATOR_CACHE_TTL_SECONDS: %v", err)
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+	return triagers.NewChecker(github_client, fsclient, githubLogin, githubRepo, ttl, config), nil
+}
+
+func labelNames(issue *github.Issue) []string {
+	var labels []string
+	for _, label := range issue.Labels {
+		labels = append(labels, label.GetName())
+	}
+	return labels
+}
+
+// Returns the name of the directive on a lowercased comment line, as used in
+// the "directives" section of the triagers config, or "" if there is none.
+func directiveName(lower_line string) string {
+	trimmed := strings.TrimSpace(lower_line)
+	switch {
+	case strings.HasPrefix(lower_line, "crbug:"), strings.HasPrefix(lower_line, "bug:"):
+		return "crbug"
+	case strings.HasPrefix(lower_line, "owner:"):
+		return "owner"
+	case strings.HasPrefix(lower_line, "cc:"):
+		return "cc"
+	case strings.HasPrefix(lower_line, "comment:"):
+		return "comment"
+	case trimmed == acceptDirective, trimmed == acceptOwnersDirective:
+		return trimmed
+	}
+	return ""
+}
+
+// Returns who last added each label to the issue, keyed by label name.
+func (app *App) labelActors(issue *github.Issue) (map[string]string, error) {
+	actors := make(map[string]string)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		events, resp, err := app.GithubClient.Issues.ListIssueEvents(
+			context.Background(), githubLogin, githubRepo, issue.GetNumber(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("ListIssueEvents: %v", err)
+		}
+		// Events are listed oldest first, so later ones win.
+		for _, event := range events {
+			if event.GetEvent() == "labeled" {
+				actors[event.GetLabel().GetName()] = event.GetActor().GetLogin()
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return actors, nil
+}
+
+// Drops component labels that were added by someone other than the bot who
+// may not triage the issue, or may not use the crbug directive on it, since
+// the label files a crbug just like the directive. Those labels are removed from the issue, and
+// a comment explains why. Returns the remaining components, and who added
+// them.
+func (app *App) FilterUnauthorizedLabels(issue *github.Issue, components []string) ([]string, string, error) {
+	if len(components) == 0 {
+		return components, "", nil
+	}
+
+	actors, err := app.labelActors(issue)
+	if err != nil {
+		return nil, "", fmt.Errorf("labelActors: %v", err)
+	}
+
+	ctx := context.Background()
+	labels := labelNames(issue)
+	var allowed []string
+	var labeler string
+	var removed []string
+	for _, component := range components {
+		label := componentLabelPrefix + component
+		actor, ok := actors[label]
+		// The bot adds labels itself when a triage rule matches.
+		if ok && actor == githubLogin {
+			allowed = append(allowed, component)
+			labeler = actor
+			continue
+		}
+		if ok {
+			allowed_label, err := app.Triagers.MayUseDirective(actor, "crbug", labels)
+			if err != nil {
+				return nil, "", fmt.Errorf("MayUseDirective: %v", err)
+			}
+			if allowed_label {
+				allowed = append(allowed, component)
+				labeler = actor
+				continue
+			}
+		} else {
+			// Without a labeled event there is nobody to check, so the label
+			// can't be trusted.
+			log.Printf("WARNING: no labeled event for %s on #%d\n", label, issue.GetNumber())
+		}
+
+		_, err = app.GithubClient.Issues.RemoveLabelForIssue(
+			ctx, githubLogin, githubRepo, issue.GetNumber(), label)
+		if err != nil {
+			return nil, "", fmt.Errorf("Issues.RemoveLabelForIssue: %v", err)
+		}
+		if ok {
+			removed = append(removed, fmt.Sprintf("`%s` (added by @%s)", label, actor))
+		} else {
+			removed = append(removed, fmt.Sprintf("`%s` (I couldn't tell who added it)", label))
+		}
+	}
+
+	if len(removed) != 0 {
+		comment_text := fmt.Sprintf("I removed %s, since only configured triagers can file crbugs for this issue.\n\n", strings.Join(removed, ", "))
+		comment_text += "A triager can add the label back if it is correct."
+		comment := &github.IssueComment{Body: &comment_text}
+		_, _, err := app.GithubClient.Issues.CreateComment(
+			ctx, githubLogin, githubRepo, issue.GetNumber(), comment)
+		if err != nil {
+			return nil, "", fmt.Errorf("Issues.CreateComment: %v", err)
+		}
+	}
+	return allowed, labeler, nil
+}
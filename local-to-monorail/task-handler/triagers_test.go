@@ -0,0 +1,98 @@
+package triage_task_handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/chromium-helper/csswg-resolutions/triagers"
+	"github.com/google/go-github/github"
+)
+
+// Serves the labeled events of issue 10, and records label removals.
+func newLabelEventsGithub(t *testing.T, actors map[string]string) (*github.Client, *[]string) {
+	var removed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/w3c/csswg-resolutions/issues/10/events":
+			var events []*github.IssueEvent
+			for label, actor := range actors {
+				events = append(events, &github.IssueEvent{
+					Event: github.String("labeled"),
+					Label: &github.Label{Name: github.String(label)},
+					Actor: &github.User{Login: github.String(actor)},
+				})
+			}
+			json.NewEncoder(w).Encode(events)
+		case r.Method == "DELETE":
+			removed = append(removed, r.URL.Path)
+			w.Write([]byte("[]"))
+		case r.Method == "POST":
+			w.Write([]byte("{}"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	base_url, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	client.BaseURL = base_url
+	return client, &removed
+}
+
+func TestFilterUnauthorizedLabels(t *testing.T) {
+	githubLogin = "w3c"
+	githubRepo = "csswg-resolutions"
+	componentLabelPrefix = "crbug:"
+	// Only leads may file crbugs, and collaborators don't count.
+	config := &triagers.Config{
+		Group:      triagers.Group{Logins: []string{"triager", "lead"}},
+		Directives: map[string]*triagers.Group{"crbug": {Logins: []string{"lead"}}},
+	}
+
+	tests := []struct {
+		name         string
+		actor        string
+		want         []string
+		want_labeler string
+	}{
+		{"bot", "w3c", []string{"Blink>Layout"}, "w3c"},
+		{"crbug group", "lead", []string{"Blink>Layout"}, "lead"},
+		{"triager outside the crbug group", "triager", nil, ""},
+		{"stranger", "someone", nil, ""},
+	}
+	for _, test := range tests {
+		client, removed := newLabelEventsGithub(t, map[string]string{"crbug:Blink>Layout": test.actor})
+		app := &App{
+			GithubClient: client,
+			Triagers:     triagers.NewChecker(client, nil, githubLogin, githubRepo, 0, config),
+		}
+		issue := &github.Issue{
+			Number: github.Int(10),
+			Labels: []github.Label{{Name: github.String("crbug:Blink>Layout")}},
+		}
+
+		components, labeler, err := app.FilterUnauthorizedLabels(issue, []string{"Blink>Layout"})
+		if err != nil {
+			t.Errorf("%s: FilterUnauthorizedLabels: %v", test.name, err)
+			continue
+		}
+		sort.Strings(components)
+		if !reflect.DeepEqual(components, test.want) || labeler != test.want_labeler {
+			t.Errorf("%s: got %v by %q, want %v by %q", test.name, components, labeler, test.want, test.want_labeler)
+		}
+		if want_removed := len(test.want) == 0; want_removed != (len(*removed) == 1) {
+			t.Errorf("%s: removed %v", test.name, *removed)
+		}
+	}
+}
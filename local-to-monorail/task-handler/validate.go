@@ -0,0 +1,143 @@
+package triage_task_handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// Maximum number of "did you mean" suggestions per problem.
+const kMaxSuggestions = 3
+
+// Levenshtein distance between two strings, ignoring case.
+func editDistance(a, b string) int {
+	ra := []rune(strings.ToLower(a))
+	rb := []rune(strings.ToLower(b))
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// Returns the candidates that are close to the input, closest first.
+func suggest(input string, candidates []string) []string {
+	max_distance := len(input) / 4
+	if max_distance < 2 {
+		max_distance = 2
+	}
+
+	type match struct {
+		candidate string
+		distance  int
+	}
+	var matches []match
+	for _, candidate := range candidates {
+		if distance := editDistance(input, candidate); distance <= max_distance {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	var results []string
+	for i := 0; i < len(matches) && i < kMaxSuggestions; i++ {
+		results = append(results, matches[i].candidate)
+	}
+	return results
+}
+
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" Did you mean `%s`?", strings.Join(suggestions, "` or `"))
+}
+
+// Checks the components and users in the directive against the bug tracker.
+// Returns a human readable description of each problem found.
+func (app *App) ValidateDirective(directive *Directive) ([]string, error) {
+	ctx := context.Background()
+	var problems []string
+
+	if len(directive.Components) != 0 {
+		components, err := app.BugTracker.ListComponents(ctx, "chromium")
+		if err != nil {
+			return nil, fmt.Errorf("ListComponents: %v", err)
+		}
+
+		component_set := make(map[string]bool)
+		for _, component := range components {
+			component_set[component] = true
+		}
+		for _, component := range directive.Components {
+			if component_set[component] {
+				continue
+			}
+			problems = append(problems, fmt.Sprintf(
+				"`%s%s` is not a known component.%s",
+				componentLabelPrefix, component, didYouMean(suggest(component, components))))
+		}
+		// Issue Tracker issues live in exactly one component.
+		if bugTracker == "issuetracker" && len(directive.Components) > 1 {
+			problems = append(problems, fmt.Sprintf(
+				"Crbugs can only have one component, but there are %d.", len(directive.Components)))
+		}
+	}
+
+	users := directive.CcList
+	if directive.Owner != "" {
+		users = append([]string{directive.Owner}, users...)
+	}
+	for _, user := range users {
+		exists, err := app.BugTracker.UserExists(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("UserExists: %v", err)
+		}
+		if !exists {
+			problems = append(problems, fmt.Sprintf("`%s` is not a known user.", user))
+		}
+	}
+	return problems, nil
+}
+
+// Replies to the triager with the problems found in their directive. The issue
+// is left open so that it can be fixed up.
+func (app *App) CommentDirectiveProblems(ghissue *github.Issue, problems []string) error {
+	comment_text := "I could not file a crbug for this issue:\n\n"
+	for _, problem := range problems {
+		comment_text += fmt.Sprintf("* %s\n", problem)
+	}
+	comment_text += "\nPlease fix the labels or comments above, and I will try again."
+
+	comment := &github.IssueComment{Body: &comment_text}
+	_, _, err := app.GithubClient.Issues.CreateComment(
+		context.Background(), githubLogin, githubRepo, ghissue.GetNumber(), comment)
+	if err != nil {
+		return fmt.Errorf("Issues.CreateComment: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package triage_task_handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"Blink>Layout", "Blink>Layout", 0},
+		{"Blink>Layout", "blink>layout", 0},
+		{"Blink>Layuot", "Blink>Layout", 2},
+		{"Blink>Layot", "Blink>Layout", 1},
+		{"kitten", "sitting", 3},
+		{"ünï", "UNI", 2},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) is %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	components := []string{"Blink>Layout", "Blink>Layout>Grid", "Blink>Layout>Flexbox",
+		"Blink>CSS", "Blink>Fonts", "Internals>Compositing"}
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"Blink>Layot", []string{"Blink>Layout"}},
+		{"blink>css", []string{"Blink>CSS"}},
+		{"Blink>Layout>Gird", []string{"Blink>Layout>Grid"}},
+		// Long inputs allow more edits.
+		{"Blink>Layout>Flex", []string{"Blink>Layout>Flexbox", "Blink>Layout>Grid"}},
+		{"Blink>Fnt", []string{"Blink>Fonts"}},
+		{"Graphics", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if got := suggest(test.input, components); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("suggest(%q) is %q, want %q", test.input, got, test.want)
+		}
+	}
+
+	// Closest first, and at most kMaxSuggestions.
+	users := []string{"abcd@x.org", "abce@x.org", "abzz@x.org", "abcf@x.org", "abcg@x.org"}
+	want := []string{"abcd@x.org", "abce@x.org", "abcf@x.org"}
+	if got := suggest("abcd@x.org", users); !reflect.DeepEqual(got, want) {
+		t.Errorf("suggest is %q, want %q", got, want)
+	}
+}
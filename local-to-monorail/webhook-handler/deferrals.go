@@ -0,0 +1,77 @@
+package webhook_handler_cf
+
+import (
+  "fmt"
+  "log"
+  "net/http"
+  "strings"
+  "time"
+
+  "github.com/chromium-helper/csswg-resolutions/fsresolutions"
+)
+
+// Schedules a triage task for every issue whose deferral is due. The task
+// handler reopens the issue if needed and reminds the triager.
+// Returns the issues that were scheduled.
+func RunDeferralReminders(now time.Time) ([]string, error) {
+  fsclient, err := fsresolutions.NewClient(gcpProjectId, gcpFsCollection)
+  if err != nil {
+    return nil, fmt.Errorf("fsresolutions.NewClient: %v", err)
+  }
+  defer fsclient.Close()
+
+  fsdatas, err := fsclient.LoadDataWithDueDeferrals(now)
+  if err != nil {
+    return nil, fmt.Errorf("LoadDataWithDueDeferrals: %v", err)
+  }
+
+  var scheduled []string
+  var failures []string
+  for _, fsdata := range fsdatas {
+    // A task is already on its way, and will see the deferral is due.
+    if fsdata.HasPendingTriageEvents || fsdata.Version != fsresolutions.Version {
+      continue
+    }
+
+    task_name, err := ScheduleTask(fsdata, now)
+    if err != nil {
+      failures = append(failures,
+          fmt.Sprintf("#%d: ScheduleTask: %v", fsdata.CsswgResolutionsId, err))
+      continue
+    }
+    fsdata.HasPendingTriageEvents = true
+    fsdata.PendingTaskName = task_name
+    fsdata.PendingSince = now
+    fsdata.PendingUpdateTime = now
+    err = fsclient.UpdateDataSetPendingTask(
+        fmt.Sprintf("%d", fsdata.CsswgDraftsId), fsdata)
+    if err != nil {
+      failures = append(failures, fmt.Sprintf("#%d: UpdateDataSetPendingTask: %v",
+          fsdata.CsswgResolutionsId, err))
+      continue
+    }
+    scheduled = append(scheduled, fmt.Sprintf("#%d (deferred until %s)",
+        fsdata.CsswgResolutionsId, fsdata.DeferredUntil.Format("2006-01-02")))
+  }
+
+  if len(failures) != 0 {
+    return scheduled, fmt.Errorf("%d failures:\n%s",
+        len(failures), strings.Join(failures, "\n"))
+  }
+  return scheduled, nil
+}
+
+// Entry point for the daily deferral job (e.g. from Cloud Scheduler).
+func HandleDeferralReminders(w http.ResponseWriter, r *http.Request) {
+  scheduled, err := RunDeferralReminders(time.Now())
+  text := fmt.Sprintf("Scheduled %d deferred issues\n", len(scheduled))
+  for _, line := range scheduled {
+    text += fmt.Sprintf("scheduled: %s\n", line)
+  }
+  log.Printf("deferral report:\n%s", text)
+  if err != nil {
+    log.Printf("RunDeferralReminders: ERROR: %v\n", err)
+    w.WriteHeader(http.StatusInternalServerError)
+  }
+  fmt.Fprint(w, text)
+}
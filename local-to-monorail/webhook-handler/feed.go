@@ -0,0 +1,236 @@
+package webhook_handler_cf
+
+import (
+  "bytes"
+  "crypto/sha256"
+  "encoding/xml"
+  "fmt"
+  "html"
+  "log"
+  "net/http"
+  "net/url"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/chromium-helper/csswg-resolutions/fsresolutions"
+  "github.com/chromium-helper/csswg-resolutions/reports"
+)
+
+// Number of entries in the feed, unless the limit query parameter says
+// otherwise.
+const kDefaultFeedLimit = 50
+const kMaxFeedLimit = 500
+
+// How long caches may serve the feed without asking again.
+const kFeedMaxAgeSeconds = 300
+
+type atomLink struct {
+  Rel string `xml:"rel,attr,omitempty"`
+  Href string `xml:"href,attr"`
+  Title string `xml:"title,attr,omitempty"`
+}
+
+type atomContent struct {
+  Type string `xml:"type,attr"`
+  Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+  Id string `xml:"id"`
+  Title string `xml:"title"`
+  Updated string `xml:"updated"`
+  Links []atomLink `xml:"link"`
+  Categories []atomCategory `xml:"category"`
+  Content atomContent `xml:"content"`
+}
+
+type atomCategory struct {
+  Term string `xml:"term,attr"`
+}
+
+type atomFeed struct {
+  XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+  Id string `xml:"id"`
+  Title string `xml:"title"`
+  Updated string `xml:"updated"`
+  Author struct {
+    Name string `xml:"name"`
+  } `xml:"author"`
+  Links []atomLink `xml:"link"`
+  Entries []*atomEntry `xml:"entry"`
+  // Same as Updated, for Last-Modified
+  modified time.Time
+}
+
+// A resolution comment, with the issue it was recorded on.
+type feedItem struct {
+  Data *fsresolutions.FSResolutionData
+  Resolution *fsresolutions.RecordedResolution
+}
+
+func createFeedEntry(item *feedItem) *atomEntry {
+  data := item.Data
+  title := data.Title
+  if title == "" {
+    title = fmt.Sprintf("%s#%d", reports.DraftsRepo, data.CsswgDraftsId)
+  }
+
+  body := ""
+  for _, text := range item.Resolution.Text {
+    body += fmt.Sprintf("<blockquote>%s</blockquote>\n",
+        html.EscapeString(strings.TrimSpace(text)))
+  }
+  links := []atomLink{
+    { Rel: "alternate", Href: item.Resolution.CommentURL,
+      Title: "csswg-drafts comment" },
+    { Rel: "related", Href: reports.TrackingIssueURL(data),
+      Title: "Tracking issue" },
+  }
+  body += "<ul>\n"
+  body += fmt.Sprintf("<li><a href=\"%s\">csswg-drafts comment</a></li>\n",
+      html.EscapeString(item.Resolution.CommentURL))
+  body += fmt.Sprintf("<li><a href=\"%s\">Tracking issue</a>: %s</li>\n",
+      reports.TrackingIssueURL(data), html.EscapeString(reports.TriageState(data)))
+  if crbug := reports.CrbugURL(data); crbug != "" {
+    links = append(links, atomLink{ Rel: "related", Href: crbug, Title: "crbug" })
+    body += fmt.Sprintf("<li><a href=\"%s\">crbug.com/%d</a>", crbug, data.CrbugId)
+    if data.CrbugStatus != "" {
+      body += fmt.Sprintf(" (%s)", html.EscapeString(data.CrbugStatus))
+    }
+    body += "</li>\n"
+  }
+  body += "</ul>\n"
+
+  entry := &atomEntry{
+    Id: item.Resolution.CommentURL,
+    Title: title,
+    Updated: item.Resolution.Time.UTC().Format(time.RFC3339),
+    Links: links,
+    Content: atomContent{ Type: "html", Body: body },
+  }
+  for _, label := range data.SpecLabels {
+    entry.Categories = append(entry.Categories, atomCategory{ Term: label })
+  }
+  return entry
+}
+
+// Returns whether the data has one of the spec labels or spec names, or true
+// if there are none.
+func matchesFeedSpecs(specs []string) func(*fsresolutions.FSResolutionData) bool {
+  return func(data *fsresolutions.FSResolutionData) bool {
+    if len(specs) == 0 {
+      return true
+    }
+    for _, spec := range specs {
+      filter := &reports.Filter{ Spec: spec }
+      if filter.Matches(data) {
+        return true
+      }
+    }
+    return false
+  }
+}
+
+// The feed id stays the same for a set of specs, whatever the limit or the
+// order of the spec parameters.
+func feedId(r *http.Request, specs []string) string {
+  id := url.URL{ Scheme: "https", Host: r.Host, Path: r.URL.Path }
+  if len(specs) != 0 {
+    sorted := append([]string{}, specs...)
+    sort.Strings(sorted)
+    id.RawQuery = url.Values{ "spec": sorted }.Encode()
+  }
+  return id.String()
+}
+
+// Builds the feed from the newest resolutions of the data.
+func createFeed(datas []*fsresolutions.FSResolutionData, specs []string,
+                limit int, id, self_url string) *atomFeed {
+  var items []*feedItem
+  for _, data := range datas {
+    for _, resolution := range data.Resolutions {
+      items = append(items, &feedItem{ Data: data, Resolution: resolution })
+    }
+  }
+  sort.Slice(items, func(i, j int) bool {
+    return items[i].Resolution.Time.After(items[j].Resolution.Time)
+  })
+  if len(items) > limit {
+    items = items[:limit]
+  }
+
+  feed := &atomFeed{
+    Id: id,
+    Title: "CSSWG resolutions",
+    Links: []atomLink{ { Rel: "self", Href: self_url } },
+  }
+  if len(specs) != 0 {
+    feed.Title += " for " + strings.Join(specs, ", ")
+  }
+  feed.Author.Name = reports.ResolutionsRepo
+  // Atom requires an updated time even for an empty feed.
+  updated := time.Unix(0, 0)
+  if len(items) != 0 {
+    updated = items[0].Resolution.Time
+  }
+  feed.Updated = updated.UTC().Format(time.RFC3339)
+  feed.modified = updated
+  for _, item := range items {
+    feed.Entries = append(feed.Entries, createFeedEntry(item))
+  }
+  return feed
+}
+
+// Serves an Atom feed of recorded resolutions. Query parameters:
+//   spec: only resolutions with this spec label or spec name; may be repeated
+//   limit: the maximum number of entries
+func HandleAtomFeed(w http.ResponseWriter, r *http.Request) {
+  limit := kDefaultFeedLimit
+  if value := r.URL.Query().Get("limit"); value != "" {
+    n, err := strconv.Atoi(value)
+    if err != nil || n <= 0 || n > kMaxFeedLimit {
+      http.Error(w, fmt.Sprintf("limit must be 1 to %d", kMaxFeedLimit),
+          http.StatusBadRequest)
+      return
+    }
+    limit = n
+  }
+  specs := r.URL.Query()["spec"]
+
+  fsclient, err := fsresolutions.NewClient(gcpProjectId, gcpFsCollection)
+  if err != nil {
+    log.Printf("fsresolutions.NewClient: ERROR: %v\n", err)
+    w.WriteHeader(http.StatusInternalServerError)
+    return
+  }
+  defer fsclient.Close()
+
+  // Each doc has at least one resolution, so the newest resolutions are all
+  // in the limit docs with the newest latest resolution.
+  datas, err := fsclient.LoadDataByLatestResolution(matchesFeedSpecs(specs), limit)
+  if err != nil {
+    log.Printf("LoadDataByLatestResolution: ERROR: %v\n", err)
+    w.WriteHeader(http.StatusInternalServerError)
+    return
+  }
+
+  self_url := "https://" + r.Host + r.URL.RequestURI()
+  feed := createFeed(datas, specs, limit, feedId(r, specs), self_url)
+  output, err := xml.MarshalIndent(feed, "", "  ")
+  if err != nil {
+    log.Printf("xml.MarshalIndent: ERROR: %v\n", err)
+    w.WriteHeader(http.StatusInternalServerError)
+    return
+  }
+  output = append([]byte(xml.Header), output...)
+
+  // Entries only change when a resolution is recorded or its issue is
+  // triaged, so readers polling every few minutes can be served from caches.
+  // ServeContent answers conditional requests from the ETag and Last-Modified.
+  w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+  w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", kFeedMaxAgeSeconds))
+  w.Header().Set("ETag", fmt.Sprintf("\"%x\"", sha256.Sum256(output)))
+  http.ServeContent(w, r, "", feed.modified, bytes.NewReader(output))
+}
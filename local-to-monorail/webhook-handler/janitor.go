@@ -0,0 +1,199 @@
+package webhook_handler_cf
+
+import (
+  "context"
+  "fmt"
+  "log"
+  "net/http"
+  "strings"
+  "time"
+
+  "cloud.google.com/go/cloudtasks/apiv2/cloudtaskspb"
+  "github.com/chromium-helper/csswg-resolutions/fsresolutions"
+  "google.golang.org/grpc/codes"
+  "google.golang.org/grpc/status"
+  cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
+)
+
+// STUCK_TRIAGE_THRESHOLD_SECONDS: (optional) how long a pending triage flag
+//    can go without its task running before the janitor steps in. Defaults to
+//    an hour past the maximum triage delay.
+var kStuckTriageThreshold =
+    durationFromEnv("STUCK_TRIAGE_THRESHOLD_SECONDS", kMaxTriageDelay + time.Hour)
+
+// WEBHOOK_DELIVERY_RETENTION_SECONDS: (optional) how long recorded webhook
+//    deliveries are kept for deduplication and replay. Defaults to 30 days.
+var kWebhookDeliveryRetention =
+    durationFromEnv("WEBHOOK_DELIVERY_RETENTION_SECONDS", 30 * 24 * time.Hour)
+
+// What the janitor did, one line per issue.
+type JanitorReport struct {
+  Rescheduled []string
+  Cleared []string
+  StillPending []string
+  // Number of webhook deliveries past kWebhookDeliveryRetention
+  DeletedDeliveries int
+}
+
+func (r *JanitorReport) String() string {
+  text := fmt.Sprintf("Rescheduled %d, cleared %d, still pending %d, deleted %d deliveries\n",
+      len(r.Rescheduled), len(r.Cleared), len(r.StillPending), r.DeletedDeliveries)
+  for _, line := range r.Rescheduled {
+    text += fmt.Sprintf("rescheduled: %s\n", line)
+  }
+  for _, line := range r.Cleared {
+    text += fmt.Sprintf("cleared: %s\n", line)
+  }
+  for _, line := range r.StillPending {
+    text += fmt.Sprintf("still pending: %s\n", line)
+  }
+  return text
+}
+
+// Returns true if the task is still in the queue.
+func taskExists(name string) (bool, error) {
+  ctx := context.Background()
+  client, err := cloudtasks.NewClient(ctx)
+  if err != nil {
+    return false, fmt.Errorf("cloudtasks.NewClient: %v", err)
+  }
+  defer client.Close()
+
+  _, err = client.GetTask(ctx, &cloudtaskspb.GetTaskRequest{ Name: name })
+  if status.Code(err) == codes.NotFound {
+    return false, nil
+  }
+  if err != nil {
+    return false, fmt.Errorf("GetTask: %v", err)
+  }
+  return true, nil
+}
+
+// The task queue calls the janitor makes, so that tests can fake them.
+type taskQueue interface {
+  TaskExists(name string) (bool, error)
+  ScheduleTask(fsdata *fsresolutions.FSResolutionData,
+               schedule_time time.Time) (string, error)
+}
+
+type cloudTaskQueue struct{}
+
+func (cloudTaskQueue) TaskExists(name string) (bool, error) {
+  return taskExists(name)
+}
+
+func (cloudTaskQueue) ScheduleTask(fsdata *fsresolutions.FSResolutionData,
+                                   schedule_time time.Time) (string, error) {
+  return ScheduleTask(fsdata, schedule_time)
+}
+
+// The firestore write the janitor makes; fsresolutions.Client implements it.
+type pendingTaskStore interface {
+  UpdateDataSetPendingTask(name string, data *fsresolutions.FSResolutionData) error
+}
+
+// Fixes a single stale pending flag, and records what was done in the report.
+func fixStuckTriage(fsclient pendingTaskStore, queue taskQueue,
+                    fsdata *fsresolutions.FSResolutionData,
+                    now time.Time, report *JanitorReport) error {
+  docname := fmt.Sprintf("%d", fsdata.CsswgDraftsId)
+  description := fmt.Sprintf("#%d (pending since %v, task %q)",
+      fsdata.CsswgResolutionsId, fsdata.PendingSince, fsdata.PendingTaskName)
+
+  // The task handler doesn't do anything once a crbug is filed.
+  if fsdata.CrbugId != 0 {
+    fsdata.HasPendingTriageEvents = false
+    fsdata.PendingTaskName = ""
+    fsdata.PendingSince = time.Time{}
+    fsdata.PendingUpdateTime = time.Time{}
+    if err := fsclient.UpdateDataSetPendingTask(docname, fsdata); err != nil {
+      return fmt.Errorf("UpdateDataSetPendingTask: %v", err)
+    }
+    report.Cleared = append(report.Cleared, description)
+    return nil
+  }
+
+  // The task may be retrying, in which case we leave it be.
+  if fsdata.PendingTaskName != "" {
+    exists, err := queue.TaskExists(fsdata.PendingTaskName)
+    if err != nil {
+      return fmt.Errorf("taskExists: %v", err)
+    }
+    if exists {
+      report.StillPending = append(report.StillPending, description)
+      return nil
+    }
+  }
+
+  task_name, err := queue.ScheduleTask(fsdata, now)
+  if err != nil {
+    return fmt.Errorf("ScheduleTask: %v", err)
+  }
+  if fsdata.PendingSince.IsZero() {
+    fsdata.PendingSince = now
+  }
+  fsdata.PendingTaskName = task_name
+  fsdata.PendingUpdateTime = now
+  if err = fsclient.UpdateDataSetPendingTask(docname, fsdata); err != nil {
+    return fmt.Errorf("UpdateDataSetPendingTask: %v", err)
+  }
+  report.Rescheduled = append(report.Rescheduled, description)
+  return nil
+}
+
+// Finds pending triage flags that haven't been handled for longer than
+// kStuckTriageThreshold, and either reschedules their task or clears them.
+// Also deletes webhook deliveries older than kWebhookDeliveryRetention.
+func RunStuckTriageJanitor(now time.Time) (*JanitorReport, error) {
+  fsclient, err := fsresolutions.NewClient(gcpProjectId, gcpFsCollection)
+  if err != nil {
+    return nil, fmt.Errorf("fsresolutions.NewClient: %v", err)
+  }
+  defer fsclient.Close()
+
+  fsdatas, err := fsclient.LoadDataWithPendingTriageEvents()
+  if err != nil {
+    return nil, fmt.Errorf("LoadDataWithPendingTriageEvents: %v", err)
+  }
+
+  report := &JanitorReport{}
+  var failures []string
+  for _, fsdata := range fsdatas {
+    // Flags set before we recorded the update time are always stale.
+    if !fsdata.PendingUpdateTime.IsZero() &&
+       now.Sub(fsdata.PendingUpdateTime) < kStuckTriageThreshold {
+      continue
+    }
+    if err := fixStuckTriage(fsclient, cloudTaskQueue{}, fsdata, now, report); err != nil {
+      failures = append(failures,
+          fmt.Sprintf("#%d: %v", fsdata.CsswgResolutionsId, err))
+    }
+  }
+
+  deleted, err := fsclient.DeleteWebhookDeliveriesBefore(now.Add(-kWebhookDeliveryRetention))
+  report.DeletedDeliveries = deleted
+  if err != nil {
+    failures = append(failures, fmt.Sprintf("DeleteWebhookDeliveriesBefore: %v", err))
+  }
+
+  if len(failures) != 0 {
+    return report, fmt.Errorf("%d failures:\n%s",
+        len(failures), strings.Join(failures, "\n"))
+  }
+  return report, nil
+}
+
+// Entry point for the periodic janitor job (e.g. from Cloud Scheduler).
+func HandleStuckTriageJanitor(w http.ResponseWriter, r *http.Request) {
+  report, err := RunStuckTriageJanitor(time.Now())
+  if report != nil {
+    log.Printf("janitor report:\n%s", report)
+  }
+  if err != nil {
+    log.Printf("RunStuckTriageJanitor: ERROR: %v\n", err)
+    w.WriteHeader(http.StatusInternalServerError)
+  }
+  if report != nil {
+    fmt.Fprint(w, report)
+  }
+}
@@ -0,0 +1,150 @@
+package webhook_handler_cf
+
+import (
+  "errors"
+  "strings"
+  "testing"
+  "time"
+
+  "github.com/chromium-helper/csswg-resolutions/fsresolutions"
+)
+
+type fakeTaskQueue struct {
+  // Tasks still in the queue
+  existing map[string]bool
+  exists_err error
+  checked []string
+  scheduled []time.Time
+}
+
+func (q *fakeTaskQueue) TaskExists(name string) (bool, error) {
+  q.checked = append(q.checked, name)
+  return q.existing[name], q.exists_err
+}
+
+func (q *fakeTaskQueue) ScheduleTask(fsdata *fsresolutions.FSResolutionData,
+                                     schedule_time time.Time) (string, error) {
+  q.scheduled = append(q.scheduled, schedule_time)
+  return "queue/tasks/new", nil
+}
+
+// Records the pending task fields of each write, by doc name.
+type fakePendingTaskStore struct {
+  updates map[string]fsresolutions.FSResolutionData
+}
+
+func (s *fakePendingTaskStore) UpdateDataSetPendingTask(
+    name string, data *fsresolutions.FSResolutionData) error {
+  s.updates[name] = *data
+  return nil
+}
+
+func TestFixStuckTriage(t *testing.T) {
+  now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+  since := now.Add(-3 * time.Hour)
+
+  tests := []struct {
+    name string
+    data fsresolutions.FSResolutionData
+    existing map[string]bool
+    // Which list of the report the issue lands in
+    want_action string
+    want_checked int
+    want_scheduled int
+    // The pending task fields written, if any
+    want_update *fsresolutions.FSResolutionData
+  }{
+    {
+      name: "crbug filed",
+      data: fsresolutions.FSResolutionData{ CrbugId: 123,
+          PendingTaskName: "queue/tasks/old", PendingSince: since },
+      want_action: "cleared",
+      want_update: &fsresolutions.FSResolutionData{},
+    },
+    {
+      name: "task still queued",
+      data: fsresolutions.FSResolutionData{ HasPendingTriageEvents: true,
+          PendingTaskName: "queue/tasks/old", PendingSince: since },
+      existing: map[string]bool{ "queue/tasks/old": true },
+      want_action: "still pending",
+      want_checked: 1,
+    },
+    {
+      name: "task gone",
+      data: fsresolutions.FSResolutionData{ HasPendingTriageEvents: true,
+          PendingTaskName: "queue/tasks/old", PendingSince: since },
+      want_action: "rescheduled",
+      want_checked: 1,
+      want_scheduled: 1,
+      want_update: &fsresolutions.FSResolutionData{ HasPendingTriageEvents: true,
+          PendingTaskName: "queue/tasks/new", PendingSince: since,
+          PendingUpdateTime: now },
+    },
+    {
+      name: "flag set before task names were recorded",
+      data: fsresolutions.FSResolutionData{ HasPendingTriageEvents: true },
+      want_action: "rescheduled",
+      want_scheduled: 1,
+      want_update: &fsresolutions.FSResolutionData{ HasPendingTriageEvents: true,
+          PendingTaskName: "queue/tasks/new", PendingSince: now,
+          PendingUpdateTime: now },
+    },
+  }
+  for _, test := range tests {
+    test.data.CsswgDraftsId = 5
+    test.data.CsswgResolutionsId = 10
+    store := &fakePendingTaskStore{ updates: map[string]fsresolutions.FSResolutionData{} }
+    queue := &fakeTaskQueue{ existing: test.existing }
+    report := &JanitorReport{}
+
+    if err := fixStuckTriage(store, queue, &test.data, now, report); err != nil {
+      t.Errorf("%s: fixStuckTriage: %v", test.name, err)
+      continue
+    }
+    if !strings.Contains(report.String(), "\n" + test.want_action + ": #10 ") {
+      t.Errorf("%s: report is %q, want the issue %s", test.name, report, test.want_action)
+    }
+    if len(queue.checked) != test.want_checked ||
+       len(queue.scheduled) != test.want_scheduled {
+      t.Errorf("%s: checked %v and scheduled %v", test.name, queue.checked, queue.scheduled)
+    }
+
+    update, updated := store.updates["5"]
+    if test.want_update == nil {
+      if updated {
+        t.Errorf("%s: wrote %+v, want no write", test.name, update)
+      }
+      continue
+    }
+    if !updated {
+      t.Errorf("%s: no write", test.name)
+      continue
+    }
+    if update.HasPendingTriageEvents != test.want_update.HasPendingTriageEvents ||
+       update.PendingTaskName != test.want_update.PendingTaskName ||
+       !update.PendingSince.Equal(test.want_update.PendingSince) ||
+       !update.PendingUpdateTime.Equal(test.want_update.PendingUpdateTime) {
+      t.Errorf("%s: wrote pending %v %q since %v updated %v, want %v %q since %v updated %v",
+          test.name, update.HasPendingTriageEvents, update.PendingTaskName,
+          update.PendingSince, update.PendingUpdateTime,
+          test.want_update.HasPendingTriageEvents, test.want_update.PendingTaskName,
+          test.want_update.PendingSince, test.want_update.PendingUpdateTime)
+    }
+  }
+}
+
+func TestFixStuckTriageQueueError(t *testing.T) {
+  store := &fakePendingTaskStore{ updates: map[string]fsresolutions.FSResolutionData{} }
+  queue := &fakeTaskQueue{ exists_err: errors.New("unavailable") }
+  report := &JanitorReport{}
+  data := &fsresolutions.FSResolutionData{ CsswgDraftsId: 5,
+      HasPendingTriageEvents: true, PendingTaskName: "queue/tasks/old" }
+
+  if err := fixStuckTriage(store, queue, data, time.Now(), report); err == nil {
+    t.Errorf("fixStuckTriage succeeded")
+  }
+  // We don't know whether the task is still queued, so leave the flag alone.
+  if len(queue.scheduled) != 0 || len(store.updates) != 0 {
+    t.Errorf("scheduled %v and wrote %v", queue.scheduled, store.updates)
+  }
+}
@@ -0,0 +1,451 @@
+package webhook_handler_cf
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "log"
+  "net/http"
+  "os"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/chromium-helper/csswg-resolutions/fsresolutions"
+  "github.com/chromium-helper/csswg-resolutions/suggestions"
+  "github.com/chromium-helper/csswg-resolutions/triagers"
+  "github.com/google/go-github/github"
+)
+
+// How long tracking issues may stay untriaged, and who to remind. A json file
+// of the form:
+//
+//   {
+//     "default_days": 14,
+//     "reminder_interval_days": 7,
+//     "specs": { "css-grid": 7 },
+//     "fallback_team": "w3c/chromium-css",
+//     "max_mentions": 5
+//   }
+//
+// An issue with several spec labels gets the shortest of their SLAs.
+// Reminders mention the triagers configured for the issue's specs, or else
+// the configured general triagers, or else the fallback team, and at most
+// max_mentions of them.
+type SlaConfig struct {
+  DefaultDays int `json:"default_days"`
+  ReminderIntervalDays int `json:"reminder_interval_days"`
+  Specs map[string]int `json:"specs"`
+  FallbackTeam string `json:"fallback_team"`
+  MaxMentions int `json:"max_mentions"`
+}
+
+var kDefaultSlaConfig = &SlaConfig{
+  DefaultDays: 14, ReminderIntervalDays: 7, MaxMentions: 5 }
+
+// Heading for overdue issues without any spec label in the digest.
+const kNoSpecHeading = "(no spec)"
+
+// Digest issues are found by this title prefix.
+const kDigestTitlePrefix = "Overdue triage digest: "
+
+func LoadSlaConfig(path string) (*SlaConfig, error) {
+  if path == "" {
+    return kDefaultSlaConfig, nil
+  }
+
+  contents, err := os.ReadFile(path)
+  if err != nil {
+    return nil, fmt.Errorf("ReadFile: %v", err)
+  }
+  config := *kDefaultSlaConfig
+  if err = json.Unmarshal(contents, &config); err != nil {
+    return nil, fmt.Errorf("Unmarshal: %v", err)
+  }
+  return &config, nil
+}
+
+func (c *SlaConfig) Sla(specs []string) time.Duration {
+  days := c.DefaultDays
+  for _, spec := range specs {
+    if spec_days, ok := c.Specs[spec]; ok && spec_days < days {
+      days = spec_days
+    }
+  }
+  return time.Duration(days) * 24 * time.Hour
+}
+
+func (c *SlaConfig) ReminderInterval() time.Duration {
+  return time.Duration(c.ReminderIntervalDays) * 24 * time.Hour
+}
+
+// An untriaged tracking issue that is past its SLA.
+type OverdueIssue struct {
+  FSData *fsresolutions.FSResolutionData
+  Issue *github.Issue
+  Specs []string
+  Age time.Duration
+  Sla time.Duration
+}
+
+func labelNames(issue *github.Issue) []string {
+  var labels []string
+  for _, label := range issue.Labels {
+    labels = append(labels, label.GetName())
+  }
+  return labels
+}
+
+// Returns the open tracking issues, keyed by number.
+func listOpenIssues(github_client *github.Client) (map[int]*github.Issue, error) {
+  issues := make(map[int]*github.Issue)
+  opts := &github.IssueListByRepoOptions{
+    State: "open",
+    ListOptions: github.ListOptions{ PerPage: 100 },
+  }
+  for {
+    page, resp, err := github_client.Issues.ListByRepo(
+        context.Background(), githubLogin, githubRepo, opts)
+    if err != nil {
+      return nil, fmt.Errorf("ListByRepo: %v", err)
+    }
+    for _, issue := range page {
+      issues[issue.GetNumber()] = issue
+    }
+    if resp.NextPage == 0 {
+      break
+    }
+    opts.Page = resp.NextPage
+  }
+  return issues, nil
+}
+
+// Finds open tracking issues that have no triage outcome, no crbug and no
+// deferral, and that have waited longer than their SLA since they were filed
+// or since their last deferral ended. Oldest first.
+func FindOverdueIssues(fsclient *fsresolutions.Client,
+                       github_client *github.Client,
+                       config *SlaConfig, now time.Time) ([]*OverdueIssue, error) {
+  fsdatas, err := fsclient.LoadAllData()
+  if err != nil {
+    return nil, fmt.Errorf("LoadAllData: %v", err)
+  }
+
+  open_issues, err := listOpenIssues(github_client)
+  if err != nil {
+    return nil, fmt.Errorf("listOpenIssues: %v", err)
+  }
+
+  var overdue []*OverdueIssue
+  for _, fsdata := range fsdatas {
+    if fsdata.TriageOutcome != "" || fsdata.CrbugId != 0 ||
+       !fsdata.DeferredUntil.IsZero() {
+      continue
+    }
+    issue, ok := open_issues[fsdata.CsswgResolutionsId]
+    if !ok || IsMetaIssue(issue) {
+      continue
+    }
+
+    specs := suggestions.SpecNames(labelNames(issue))
+    // A deferral restarts the clock.
+    start := issue.GetCreatedAt()
+    if fsdata.DeferralEndTime.After(start) {
+      start = fsdata.DeferralEndTime
+    }
+    age := now.Sub(start)
+    sla := config.Sla(specs)
+    if age > sla {
+      overdue = append(overdue, &OverdueIssue{
+        FSData: fsdata, Issue: issue, Specs: specs, Age: age, Sla: sla })
+    }
+  }
+  sort.Slice(overdue, func(i, j int) bool {
+    return overdue[i].Age > overdue[j].Age
+  })
+  return overdue, nil
+}
+
+// Returns "@login" and "@org/team" mentions for the triagers configured for
+// the given specs, falling back to the configured general triagers and then
+// to the fallback team. Collaborators aren't mentioned, even if they may
+// triage, since there may be many of them.
+func triageOwnerMentions(config *triagers.Config, sla_config *SlaConfig,
+                         specs []string) []string {
+  var groups []*triagers.Group
+  for _, spec := range specs {
+    if group := config.Specs[spec]; group != nil {
+      groups = append(groups, group)
+    }
+  }
+  if len(groups) == 0 {
+    groups = append(groups, &config.Group)
+  }
+
+  seen := make(map[string]bool)
+  var mentions []string
+  for _, group := range groups {
+    for _, name := range append(append([]string{}, group.Logins...), group.Teams...) {
+      if !seen[name] {
+        seen[name] = true
+        mentions = append(mentions, "@" + name)
+      }
+    }
+  }
+  if len(mentions) == 0 && sla_config.FallbackTeam != "" {
+    mentions = append(mentions, "@" + sla_config.FallbackTeam)
+  }
+  if len(mentions) > sla_config.MaxMentions {
+    mentions = mentions[:sla_config.MaxMentions]
+  }
+  return mentions
+}
+
+func days(d time.Duration) int {
+  return int(d.Hours() / 24)
+}
+
+// Posts a reminder on every overdue issue that wasn't reminded about within
+// the reminder interval. Returns the issues that were reminded about.
+func RunSlaReminders(now time.Time) ([]string, error) {
+  config, err := LoadSlaConfig(slaFile)
+  if err != nil {
+    return nil, fmt.Errorf("LoadSlaConfig: %v", err)
+  }
+  triagers_config, err := triagers.LoadConfigOrDefault(triagersFile)
+  if err != nil {
+    return nil, fmt.Errorf("LoadConfigOrDefault: %v", err)
+  }
+
+  ctx := context.Background()
+  github_client, err := newGithubClient(ctx)
+  if err != nil {
+    return nil, fmt.Errorf("newGithubClient: %v", err)
+  }
+  fsclient, err := fsresolutions.NewClient(gcpProjectId, gcpFsCollection)
+  if err != nil {
+    return nil, fmt.Errorf("fsresolutions.NewClient: %v", err)
+  }
+  defer fsclient.Close()
+
+  overdue, err := FindOverdueIssues(fsclient, github_client, config, now)
+  if err != nil {
+    return nil, fmt.Errorf("FindOverdueIssues: %v", err)
+  }
+
+  var reminded []string
+  var failures []string
+  for _, item := range overdue {
+    if now.Sub(item.FSData.SlaReminderTime) < config.ReminderInterval() {
+      continue
+    }
+
+    body := fmt.Sprintf("This issue has been waiting for triage for %d days, " +
+        "which is past its %d day SLA.", days(item.Age), days(item.Sla))
+    if mentions := triageOwnerMentions(triagers_config, config, item.Specs); len(mentions) != 0 {
+      body = fmt.Sprintf("%s %s", strings.Join(mentions, " "), body)
+    }
+    _, _, err := github_client.Issues.CreateComment(ctx, githubLogin, githubRepo,
+        item.Issue.GetNumber(), &github.IssueComment{ Body: &body })
+    if err != nil {
+      failures = append(failures,
+          fmt.Sprintf("#%d: CreateComment: %v", item.Issue.GetNumber(), err))
+      continue
+    }
+
+    item.FSData.SlaReminderTime = now
+    err = fsclient.UpdateDataSetSlaReminderTime(
+        fmt.Sprintf("%d", item.FSData.CsswgDraftsId), item.FSData)
+    if err != nil {
+      failures = append(failures, fmt.Sprintf("#%d: UpdateDataSetSlaReminderTime: %v",
+          item.Issue.GetNumber(), err))
+      continue
+    }
+    reminded = append(reminded, fmt.Sprintf("#%d", item.Issue.GetNumber()))
+  }
+
+  if len(failures) != 0 {
+    return reminded, fmt.Errorf("%d failures:\n%s",
+        len(failures), strings.Join(failures, "\n"))
+  }
+  return reminded, nil
+}
+
+// Formats the overdue issues as markdown, grouped by spec. Issues with several
+// specs are listed under each of them.
+func createOverdueDigestText(overdue []*OverdueIssue) string {
+  by_spec := make(map[string][]*OverdueIssue)
+  for _, item := range overdue {
+    if len(item.Specs) == 0 {
+      by_spec[kNoSpecHeading] = append(by_spec[kNoSpecHeading], item)
+    }
+    for _, spec := range item.Specs {
+      by_spec[spec] = append(by_spec[spec], item)
+    }
+  }
+
+  var specs []string
+  for spec := range by_spec {
+    specs = append(specs, spec)
+  }
+  sort.Strings(specs)
+
+  body := fmt.Sprintf("%d tracking issues are past their triage SLA.\n", len(overdue))
+  for _, spec := range specs {
+    body += fmt.Sprintf("\n### %s\n\n", spec)
+    for _, item := range by_spec[spec] {
+      body += fmt.Sprintf("- #%d %s (%d days old, SLA %d days)\n",
+          item.Issue.GetNumber(), item.Issue.GetTitle(),
+          days(item.Age), days(item.Sla))
+    }
+  }
+  return body
+}
+
+// Returns the open digest issues.
+func listOpenDigests(github_client *github.Client) ([]*github.Issue, error) {
+  var digests []*github.Issue
+  opts := &github.IssueListByRepoOptions{
+    State: "open",
+    Labels: []string{ metaLabel },
+    ListOptions: github.ListOptions{ PerPage: 100 },
+  }
+  for {
+    page, resp, err := github_client.Issues.ListByRepo(
+        context.Background(), githubLogin, githubRepo, opts)
+    if err != nil {
+      return nil, fmt.Errorf("ListByRepo: %v", err)
+    }
+    for _, issue := range page {
+      if strings.HasPrefix(issue.GetTitle(), kDigestTitlePrefix) {
+        digests = append(digests, issue)
+      }
+    }
+    if resp.NextPage == 0 {
+      break
+    }
+    opts.Page = resp.NextPage
+  }
+  return digests, nil
+}
+
+// Comments on a digest issue and closes it.
+func closeDigest(github_client *github.Client, number int, comment string) error {
+  ctx := context.Background()
+  _, _, err := github_client.Issues.CreateComment(ctx, githubLogin, githubRepo,
+      number, &github.IssueComment{ Body: &comment })
+  if err != nil {
+    return fmt.Errorf("CreateComment: %v", err)
+  }
+  closed := "closed"
+  _, _, err = github_client.Issues.Edit(ctx, githubLogin, githubRepo,
+      number, &github.IssueRequest{ State: &closed })
+  if err != nil {
+    return fmt.Errorf("Edit: %v", err)
+  }
+  return nil
+}
+
+// Keeps one digest issue listing all overdue issues, labeled meta so that the
+// bot leaves it alone. This week's digest is edited if it exists, and filed
+// otherwise; older digests are closed. Returns the digest's number, or 0 if
+// nothing is overdue, in which case all digests are closed.
+func RunOverdueDigest(now time.Time) (int, error) {
+  config, err := LoadSlaConfig(slaFile)
+  if err != nil {
+    return 0, fmt.Errorf("LoadSlaConfig: %v", err)
+  }
+
+  ctx := context.Background()
+  github_client, err := newGithubClient(ctx)
+  if err != nil {
+    return 0, fmt.Errorf("newGithubClient: %v", err)
+  }
+  fsclient, err := fsresolutions.NewClient(gcpProjectId, gcpFsCollection)
+  if err != nil {
+    return 0, fmt.Errorf("fsresolutions.NewClient: %v", err)
+  }
+  defer fsclient.Close()
+
+  overdue, err := FindOverdueIssues(fsclient, github_client, config, now)
+  if err != nil {
+    return 0, fmt.Errorf("FindOverdueIssues: %v", err)
+  }
+  digests, err := listOpenDigests(github_client)
+  if err != nil {
+    return 0, fmt.Errorf("listOpenDigests: %v", err)
+  }
+
+  number := 0
+  close_comment := "Nothing is overdue anymore."
+  if len(overdue) != 0 {
+    title := fmt.Sprintf("%sweek of %s", kDigestTitlePrefix,
+        now.AddDate(0, 0, -int(now.Weekday())).Format("2006-01-02"))
+    body := createOverdueDigestText(overdue)
+    for _, digest := range digests {
+      if digest.GetTitle() == title {
+        number = digest.GetNumber()
+      }
+    }
+    if number != 0 {
+      _, _, err = github_client.Issues.Edit(ctx, githubLogin, githubRepo,
+          number, &github.IssueRequest{ Body: &body })
+      if err != nil {
+        return 0, fmt.Errorf("Issues.Edit: %v", err)
+      }
+    } else {
+      labels := []string{ metaLabel }
+      issue, _, err := github_client.Issues.Create(ctx, githubLogin, githubRepo,
+          &github.IssueRequest{ Title: &title, Body: &body, Labels: &labels })
+      if err != nil {
+        return 0, fmt.Errorf("Issues.Create: %v", err)
+      }
+      number = issue.GetNumber()
+    }
+    close_comment = fmt.Sprintf("Superseded by #%d.", number)
+  }
+
+  var failures []string
+  for _, digest := range digests {
+    if digest.GetNumber() == number {
+      continue
+    }
+    if err := closeDigest(github_client, digest.GetNumber(), close_comment); err != nil {
+      failures = append(failures, fmt.Sprintf("#%d: %v", digest.GetNumber(), err))
+    }
+  }
+  if len(failures) != 0 {
+    return number, fmt.Errorf("closing old digests: %s", strings.Join(failures, ", "))
+  }
+  return number, nil
+}
+
+// Entry point for the daily SLA reminder job (e.g. from Cloud Scheduler).
+func HandleSlaReminders(w http.ResponseWriter, r *http.Request) {
+  reminded, err := RunSlaReminders(time.Now())
+  text := fmt.Sprintf("Reminded about %d overdue issues: %s\n",
+      len(reminded), strings.Join(reminded, ", "))
+  log.Printf("sla report: %s", text)
+  if err != nil {
+    log.Printf("RunSlaReminders: ERROR: %v\n", err)
+    w.WriteHeader(http.StatusInternalServerError)
+  }
+  fmt.Fprint(w, text)
+}
+
+// Entry point for the weekly overdue digest job.
+func HandleOverdueDigest(w http.ResponseWriter, r *http.Request) {
+  number, err := RunOverdueDigest(time.Now())
+  if err != nil {
+    log.Printf("RunOverdueDigest: ERROR: %v\n", err)
+    w.WriteHeader(http.StatusInternalServerError)
+    if number == 0 {
+      return
+    }
+  }
+  if number == 0 {
+    fmt.Fprint(w, "Nothing is overdue\n")
+    return
+  }
+  fmt.Fprintf(w, "Digest is #%d\n", number)
+}
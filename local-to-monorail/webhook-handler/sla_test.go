@@ -0,0 +1,44 @@
+package webhook_handler_cf
+
+import (
+  "reflect"
+  "testing"
+
+  "github.com/chromium-helper/csswg-resolutions/triagers"
+)
+
+func TestTriageOwnerMentions(t *testing.T) {
+  configured := &triagers.Config{
+    Collaborators: true,
+    Group: triagers.Group{ Logins: []string{"lead"}, Teams: []string{"w3c/css"} },
+    Specs: map[string]*triagers.Group{
+      "css-grid": { Logins: []string{"grid-owner", "lead"} },
+      "css-align": { Teams: []string{"w3c/align"} },
+    },
+  }
+  sla_config := &SlaConfig{ FallbackTeam: "w3c/fallback", MaxMentions: 3 }
+
+  tests := []struct {
+    name string
+    config *triagers.Config
+    specs []string
+    want []string
+  }{
+    {"spec triagers", configured, []string{"css-grid"}, []string{"@grid-owner", "@lead"}},
+    {"capped", configured, []string{"css-grid", "css-align", "css-fonts"},
+        []string{"@grid-owner", "@lead", "@w3c/align"}},
+    {"general triagers", configured, []string{"css-fonts"}, []string{"@lead", "@w3c/css"}},
+    // Collaborators may triage, but there may be too many to mention.
+    {"collaborators only", triagers.DefaultConfig, []string{"css-grid"}, []string{"@w3c/fallback"}},
+  }
+  for _, test := range tests {
+    got := triageOwnerMentions(test.config, sla_config, test.specs)
+    if !reflect.DeepEqual(got, test.want) {
+      t.Errorf("%s: mentions are %q, want %q", test.name, got, test.want)
+    }
+  }
+
+  if got := triageOwnerMentions(triagers.DefaultConfig, kDefaultSlaConfig, nil); len(got) != 0 {
+    t.Errorf("mentions without a fallback team are %q", got)
+  }
+}
@@ -0,0 +1,75 @@
+package webhook_handler_cf
+
+import (
+  "context"
+  "fmt"
+  "sync"
+
+  "github.com/chromium-helper/csswg-resolutions/fsresolutions"
+  "github.com/chromium-helper/csswg-resolutions/triagers"
+  "github.com/google/go-github/github"
+  "golang.org/x/oauth2"
+  gcpsm "cloud.google.com/go/secretmanager/apiv1"
+  gcpsmpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+)
+
+var kCollaboratorCacheTTL =
+    durationFromEnv("COLLABORATOR_CACHE_TTL_SECONDS", triagers.DefaultCacheTTL)
+
+func getGithubAPIToken(ctx context.Context) (string, error) {
+  client, err := gcpsm.NewClient(ctx)
+  if err != nil {
+    return "", fmt.Errorf("gcpsm.NewClient: %v", err)
+  }
+  defer client.Close()
+
+  req := &gcpsmpb.AccessSecretVersionRequest{
+    Name: fmt.Sprintf("projects/%s/secrets/%s/versions/latest",
+        gcpProjectId, gcpGithubAPIKeySecret),
+  }
+  secret, err := client.AccessSecretVersion(ctx, req)
+  if err != nil {
+    return "", fmt.Errorf("gcpsm.AccessSecretVersion: %v", err)
+  }
+  return string(secret.Payload.GetData()), nil
+}
+
+// The github client is kept between requests, since function instances serve
+// many webhooks and reading the token costs a Secret Manager call.
+var (
+  githubClientMutex sync.Mutex
+  githubClient *github.Client
+)
+
+func newGithubClient(ctx context.Context) (*github.Client, error) {
+  githubClientMutex.Lock()
+  defer githubClientMutex.Unlock()
+  if githubClient != nil {
+    return githubClient, nil
+  }
+
+  token, err := getGithubAPIToken(ctx)
+  if err != nil {
+    return nil, fmt.Errorf("getGithubAPIToken: %v", err)
+  }
+  githubClient = github.NewClient(oauth2.NewClient(context.Background(),
+      oauth2.StaticTokenSource(&oauth2.Token{ AccessToken: token })))
+  return githubClient, nil
+}
+
+// Returns true if the user may triage an issue with the given labels.
+func IsTriagerFor(fsclient *fsresolutions.Client, login string, labels []string) (bool, error) {
+  config, err := triagers.LoadConfigOrDefault(triagersFile)
+  if err != nil {
+    return false, fmt.Errorf("LoadConfigOrDefault: %v", err)
+  }
+
+  github_client, err := newGithubClient(context.Background())
+  if err != nil {
+    return false, fmt.Errorf("newGithubClient: %v", err)
+  }
+
+  checker := triagers.NewChecker(github_client, fsclient,
+      githubLogin, githubRepo, kCollaboratorCacheTTL, config)
+  return checker.IsTriagerFor(login, labels)
+}
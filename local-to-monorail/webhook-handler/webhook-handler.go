@@ -2,6 +2,7 @@ package webhook_handler_cf
 
 import (
   "context"
+  "crypto/subtle"
   "fmt"
   "log"
   "net/http"
@@ -12,7 +13,10 @@ import (
 
   "cloud.google.com/go/cloudtasks/apiv2/cloudtaskspb"
   "github.com/chromium-helper/csswg-resolutions/fsresolutions"
+  "github.com/chromium-helper/csswg-resolutions/listcomments"
   "github.com/google/go-github/github"
+  "google.golang.org/grpc/codes"
+  "google.golang.org/grpc/status"
   "google.golang.org/protobuf/types/known/timestamppb"
   cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
 )
@@ -20,18 +24,39 @@ import (
 // Environment that needs to be configured.
 // GITHUB_SECRET_KEY: the secret webhook key to validate payload
 // GITHUB_LOGIN: github login -- used to filter comments by this account
+// GITHUB_REPO: github repo whose collaborators may triage
+// GCP_GITHUB_API_KEY_SECRET_NAME: secret holding the github api token, used
+//    to look up collaborator permissions
+// COLLABORATOR_CACHE_TTL_SECONDS: (optional) how long to cache collaborator
+//    permissions. Defaults to an hour.
+// TRIAGERS_FILE: (optional) json file listing who may triage, see
+//    triagers.Config. Defaults to collaborators with write access.
+// SLA_FILE: (optional) json file with triage SLAs, see SlaConfig.
 // GITHUB_ACTION_LABEL_PREFIX: github label prefix that causes an action
 // GCP_PROJECT_ID: the project where this is running
 // GCP_QUEUE_LOCATION: the data centre location of the task queue
 // GCP_QUEUE_ID: the name of the task queue
 // GCP_FS_COLLECTION: the name of the firestore collection for fsresolutions
 // GCP_TASK_HANDLER_URL: the url of the cloud function which handles the tasks
-// TRIAGE_GRACE_PERIOD_SECONDS: the number of seconds to delay running the task,
-//    allowing for more triager actions
+// TRIAGE_GRACE_PERIOD_SECONDS: (optional) the number of seconds to delay
+//    running the task, allowing for more triager actions. Each new event
+//    restarts the delay. Defaults to a minute.
+// TRIAGE_MAX_DELAY_SECONDS: (optional) the maximum number of seconds between
+//    the first pending event and the task running, no matter how many events
+//    follow. Defaults to 4 times the grace period.
 // GCP_INVOKER_ACCOUNT: the account that invokes the task handler.
+// WEBHOOK_DELIVERY_CLAIM_TIMEOUT_SECONDS: (optional) how long a delivery that
+//    is being processed blocks redeliveries. Should be at least the function
+//    timeout. Defaults to 10 minutes.
+// REPLAY_SECRET_KEY: (optional) bearer token that HandleReplayDelivery
+//    requires. Replays are refused if it isn't set.
 var (
   githubSecretKey = os.Getenv("GITHUB_SECRET_KEY")
   githubLogin = os.Getenv("GITHUB_LOGIN")
+  githubRepo = os.Getenv("GITHUB_REPO")
+  gcpGithubAPIKeySecret = os.Getenv("GCP_GITHUB_API_KEY_SECRET_NAME")
+  triagersFile = os.Getenv("TRIAGERS_FILE")
+  slaFile = os.Getenv("SLA_FILE")
   githubActionLabelPrefix = os.Getenv("GITHUB_ACTION_LABEL_PREFIX")
 
   gcpProjectId = os.Getenv("GCP_PROJECT_ID")
@@ -40,11 +65,16 @@ var (
   gcpFsCollection = os.Getenv("GCP_FS_COLLECTION")
   gcpTaskHandlerUrl = os.Getenv("GCP_TASK_HANDLER_URL")
   gcpInvokerAccount = os.Getenv("GCP_INVOKER_ACCOUNT")
+  replaySecretKey = os.Getenv("REPLAY_SECRET_KEY")
 
-  kTriageGracePeriod =
-      time.Duration(mustInt(os.Getenv("TRIAGE_GRACE_PERIOD_SECONDS"))) * time.Second
+  kTriageGracePeriod = durationFromEnv("TRIAGE_GRACE_PERIOD_SECONDS", time.Minute)
+  kMaxTriageDelay = durationFromEnv("TRIAGE_MAX_DELAY_SECONDS", 4 * kTriageGracePeriod)
+  kDeliveryClaimTimeout =
+      durationFromEnv("WEBHOOK_DELIVERY_CLAIM_TIMEOUT_SECONDS", 10 * time.Minute)
 )
 
+const metaLabel = "meta"
+
 func mustInt(s string) int {
   n, err := strconv.Atoi(s)
   if err != nil {
@@ -53,34 +83,63 @@ func mustInt(s string) int {
   return n
 }
 
+// Reads a number of seconds from the environment, if set.
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+  if os.Getenv(name) == "" {
+    return fallback
+  }
+  return time.Duration(mustInt(os.Getenv(name))) * time.Second
+}
+
 // Figures out if this is likely an event that we care about.
 // Note that this doesn't have to be 100% accurate. It should acts
 // as a quick filter for things we definitely don't care about.
 func ShouldCreateTaskForIssuesEvent(event *github.IssuesEvent) bool {
-  // We only care about labeled events.
-  if event.GetAction() != "labeled" {
-    return false
-  }
-
-  if !strings.HasPrefix(event.GetLabel().GetName(), githubActionLabelPrefix) {
-    return false
+  switch event.GetAction() {
+    case "labeled", "unlabeled":
+      // Adding or removing an action label changes the triage outcome.
+      if !strings.HasPrefix(event.GetLabel().GetName(), githubActionLabelPrefix) {
+        return false
+      }
+    case "reopened":
+      // The triager wants us to take another look.
+    case "closed":
+      // A triager closed the issue, so we record that no action is needed.
+      // The bot closes issues itself once it has recorded the outcome.
+      if event.GetSender().GetLogin() == githubLogin {
+        return false
+      }
+      return !IsMetaIssue(event.GetIssue())
+    default:
+      return false
   }
 
   return ShouldCreateTaskForGithubIssue(event.GetIssue())
 }
 
 func ShouldCreateTaskForIssueCommentEvent(event *github.IssueCommentEvent) bool {
-  // Only handle "created" comments
-  if event.GetAction() != "created" {
-    return false
+  switch event.GetAction() {
+    case "created", "deleted":
+    case "edited":
+      // Skip edits that didn't touch the body, since directives live there.
+      if event.GetChanges() != nil && event.GetChanges().Body == nil {
+        return false
+      }
+    default:
+      return false
   }
 
-  // Don't handle comments made by githubLogin account
-  // We also want to filter by collaborators, but we don't want to login to
-  // check whether user is a collaborator.
+  // Don't handle comments made by githubLogin account. Comments from
+  // non-triagers are filtered later, in ProcessEvent, since that needs a
+  // github login.
   if event.GetComment().GetUser().GetLogin() == githubLogin {
     return false
   }
+  // Nor the lists of peer bugs and Chromium code, which resolutions-cli may
+  // post with someone's own token.
+  if listcomments.IsListComment(event.GetComment().GetBody()) {
+    return false
+  }
 
   return ShouldCreateTaskForGithubIssue(event.GetIssue())
 }
@@ -90,22 +149,35 @@ func ShouldCreateTaskForGithubIssue(issue *github.Issue) bool {
   if issue.GetState() != "open" {
     return false
   }
+  return !IsMetaIssue(issue)
+}
 
-  // We never handle "meta" tagged bugs
+// We never handle "meta" tagged bugs
+func IsMetaIssue(issue *github.Issue) bool {
   for _, label := range issue.Labels {
-    if label.GetName() == "meta" {
-      return false
+    if label.GetName() == metaLabel {
+      return true
     }
   }
-  return true
+  return false
 }
 
-func ScheduleTask(fsdata *fsresolutions.FSResolutionData) error {
+func queuePath() string {
+  return fmt.Sprintf("projects/%s/locations/%s/queues/%s",
+      gcpProjectId, gcpQueueLocation, gcpQueueId)
+}
+
+// Schedules a task to process the issue at the given time, and returns the
+// full name of the task. Each task gets a unique name, since cloud tasks
+// doesn't allow reusing the names of recently deleted tasks.
+func ScheduleTask(fsdata *fsresolutions.FSResolutionData,
+                  schedule_time time.Time) (string, error) {
   ctx := context.Background()
   client, err := cloudtasks.NewClient(ctx)
   if err != nil {
-    return fmt.Errorf("cloudtasks.NewClient: %v", err)
+    return "", fmt.Errorf("cloudtasks.NewClient: %v", err)
   }
+  defer client.Close()
 
   oidc_token := &cloudtaskspb.HttpRequest_OidcToken{
     OidcToken: &cloudtaskspb.OidcToken{ ServiceAccountEmail: gcpInvokerAccount },
@@ -121,89 +193,293 @@ func ScheduleTask(fsdata *fsresolutions.FSResolutionData) error {
   }
 
   task := &cloudtaskspb.Task{
+    Name: fmt.Sprintf("%s/tasks/triage-%d-%d", queuePath(),
+        fsdata.CsswgResolutionsId, time.Now().UnixNano()),
     MessageType: &cloudtaskspb.Task_HttpRequest{ HttpRequest: http_request },
-    ScheduleTime: timestamppb.New(time.Now().Add(kTriageGracePeriod)),
+    ScheduleTime: timestamppb.New(schedule_time),
   }
 
   request := &cloudtaskspb.CreateTaskRequest{
-    Parent: fmt.Sprintf("projects/%s/locations/%s/queues/%s",
-                gcpProjectId, gcpQueueLocation, gcpQueueId),
+    Parent: queuePath(),
     Task: task,
   }
 
-  _, err = client.CreateTask(ctx, request)
+  created, err := client.CreateTask(ctx, request)
   if err != nil {
-    return fmt.Errorf("CreateTask: %v", err)
+    return "", fmt.Errorf("CreateTask: %v", err)
   }
-  return nil
+  return created.GetName(), nil
 }
 
-// Processes an issue event:
-// 1. Verify that we care about this issue
-// 2. Find the firestore entry and update has_pending_triage_events
-// 3. Create a task and schedule kTriageGracePeriod seconds in the future
-func ProcessGithubIssue(github_issue_number int) error {
-  fsclient, err := fsresolutions.NewClient(gcpProjectId, gcpFsCollection)
+// Deletes a superseded task. It's fine if the task already ran or is gone,
+// since the task handler skips tasks that are no longer current.
+func DeleteTask(name string) error {
+  ctx := context.Background()
+  client, err := cloudtasks.NewClient(ctx)
   if err != nil {
-    return fmt.Errorf("fsresolutions.NewClient: %v", err)
+    return fmt.Errorf("cloudtasks.NewClient: %v", err)
   }
-  defer fsclient.Close()
+  defer client.Close()
 
+  err = client.DeleteTask(ctx, &cloudtaskspb.DeleteTaskRequest{ Name: name })
+  if err != nil && status.Code(err) != codes.NotFound {
+    return fmt.Errorf("DeleteTask: %v", err)
+  }
+  return nil
+}
+
+// Returns when the task should run: kTriageGracePeriod after the latest
+// event, but no later than kMaxTriageDelay after the first pending event.
+func taskScheduleTime(now time.Time, pending_since time.Time) time.Time {
+  schedule_time := now.Add(kTriageGracePeriod)
+  if deadline := pending_since.Add(kMaxTriageDelay);
+     schedule_time.After(deadline) {
+    schedule_time = deadline
+  }
+  if schedule_time.Before(now) {
+    schedule_time = now
+  }
+  return schedule_time
+}
+
+// Processes an issue event. The task doesn't carry any event details: it
+// re-reads the labels and comments when it runs, so edits and deletions made
+// before then are picked up.
+// 1. Verify that we care about this issue
+// 2. Find the firestore entry
+// 3. Schedule a new task kTriageGracePeriod in the future, superseding any
+//    pending task, and record it as the pending task
+func ProcessGithubIssue(fsclient *fsresolutions.Client, github_issue_number int) error {
   fsdata, err := fsclient.LoadDataByCsswgResolutionsId(github_issue_number)
   if err != nil {
     return fmt.Errorf("LoadDataByCsswgResolutionsId: %v", err)
   }
 
-  // If the version doesn't match or there's already pending events,
-  // then we shouldn't do anything.
-  if fsdata.Version != fsresolutions.Version || fsdata.HasPendingTriageEvents {
+  // If the version doesn't match, then we shouldn't do anything.
+  if fsdata.Version != fsresolutions.Version {
     return nil
   }
 
+  now := time.Now()
+  if !fsdata.HasPendingTriageEvents || fsdata.PendingSince.IsZero() {
+    fsdata.PendingSince = now
+  }
+  superseded_task := fsdata.PendingTaskName
+
+  task_name, err := ScheduleTask(fsdata, taskScheduleTime(now, fsdata.PendingSince))
+  if err != nil {
+    return fmt.Errorf("ScheduleTask: %v", err)
+  }
+
   fsdata.HasPendingTriageEvents = true
-  err = fsclient.UpdateDataSetHasPendingTriageEvents(
+  fsdata.PendingTaskName = task_name
+  fsdata.PendingUpdateTime = now
+  err = fsclient.UpdateDataSetPendingTask(
       fmt.Sprintf("%d", fsdata.CsswgDraftsId), fsdata)
   if err != nil {
-    return fmt.Errorf("UpdateDataSetHasPendingTriageEvents: %v", err)
+    return fmt.Errorf("UpdateDataSetPendingTask: %v", err)
   }
 
-  err = ScheduleTask(fsdata)
-  if err != nil {
-    return fmt.Errorf("ScheduleTask: %v", err)
+  if superseded_task != "" {
+    if err = DeleteTask(superseded_task); err != nil {
+      // Not fatal, the superseded task will notice and do nothing.
+      log.Printf("DeleteTask: ERROR: %v\n", err)
+    }
   }
   return nil
 }
 
-// Entry point for the github webhook.
-func HandleGithubWebhook(w http.ResponseWriter, r *http.Request) {
-  payload, err := github.ValidatePayload(r, []byte(githubSecretKey))
-
-  if err != nil { 
-    log.Printf("ValidatePayload: ERROR: %v\n", err);
-    return;
+// Returns whether login may triage the issue, logging why not.
+func isTriagerForIssue(fsclient *fsresolutions.Client, login string,
+                       issue *github.Issue, what string) (bool, error) {
+  var labels []string
+  for _, label := range issue.Labels {
+    labels = append(labels, label.GetName())
   }
-  event, err := github.ParseWebHook(github.WebHookType(r), payload)
+  is_triager, err := IsTriagerFor(fsclient, login, labels)
   if err != nil {
-    log.Printf("ParseWebHook: ERROR: %v\n", err);
-    return;
+    return false, fmt.Errorf("IsTriagerFor: %v", err)
+  }
+  if !is_triager {
+    log.Printf("ignoring %s by non-triager %s\n", what, login)
   }
+  return is_triager, nil
+}
 
-  err = nil
+// Processes a parsed webhook event, scheduling a task if needed.
+func ProcessEvent(fsclient *fsresolutions.Client, event interface{}) error {
   switch event := event.(type) {
     case *github.IssuesEvent:
-      if ShouldCreateTaskForIssuesEvent(event) {
-        err = ProcessGithubIssue(event.GetIssue().GetNumber())
+      if !ShouldCreateTaskForIssuesEvent(event) {
+        return nil
+      }
+      // Labels from non-triagers are removed by the task, but they
+      // shouldn't get to schedule one.
+      action := event.GetAction()
+      if action == "labeled" || action == "unlabeled" {
+        is_triager, err := isTriagerForIssue(fsclient,
+            event.GetSender().GetLogin(), event.GetIssue(), action + " event")
+        if err != nil || !is_triager {
+          return err
+        }
       }
+      return ProcessGithubIssue(fsclient, event.GetIssue().GetNumber())
     case *github.IssueCommentEvent:
-      if ShouldCreateTaskForIssueCommentEvent(event) {
-        err = ProcessGithubIssue(event.GetIssue().GetNumber())
+      if !ShouldCreateTaskForIssueCommentEvent(event) {
+        return nil
+      }
+      is_triager, err := isTriagerForIssue(fsclient,
+          event.GetComment().GetUser().GetLogin(), event.GetIssue(), "comment")
+      if err != nil || !is_triager {
+        return err
       }
+      return ProcessGithubIssue(fsclient, event.GetIssue().GetNumber())
     default:
       log.Printf("not an issue event\n");
   }
+  return nil
+}
+
+// Parses and processes a delivery, recording the outcome in the store.
+// Returns the http status to respond with.
+func processDelivery(fsclient *fsresolutions.Client, id string,
+                     delivery *fsresolutions.WebhookDelivery) int {
+  event, err := github.ParseWebHook(delivery.Event, delivery.Payload)
+  if err != nil {
+    log.Printf("ParseWebHook: ERROR: %v\n", err);
+    return http.StatusBadRequest
+  }
 
+  err = ProcessEvent(fsclient, event)
   if err != nil {
     log.Printf("process event: ERROR: %v\n", err);
+    delivery.Error = err.Error()
+  } else {
+    delivery.ProcessedTime = time.Now()
+    delivery.Error = ""
+  }
+
+  // Recording is best effort; the event itself was handled either way.
+  if id != "" {
+    if set_err := fsclient.SetWebhookDelivery(id, delivery); set_err != nil {
+      log.Printf("SetWebhookDelivery: ERROR: %v\n", set_err);
+    }
+  }
+
+  if err != nil {
+    return http.StatusInternalServerError
+  }
+  return http.StatusOK
+}
+
+// Returns true if a redelivery may process the delivery again: the previous
+// attempt failed, or it never recorded how it went and is older than
+// kDeliveryClaimTimeout, so it must have crashed or timed out.
+func isDeliveryClaimExpired(previous *fsresolutions.WebhookDelivery,
+                            now time.Time) bool {
+  if previous.Error != "" {
+    return true
+  }
+  return previous.ProcessedTime.IsZero() &&
+      now.Sub(previous.ReceivedTime) >= kDeliveryClaimTimeout
+}
+
+// Entry point for the github webhook.
+// Responds with 401 for bad signatures, 400 for bad payloads and 500 if
+// processing failed, so that failures show up in github's delivery log.
+// Deliveries that were already received are skipped, so that redeliveries
+// are idempotent, unless processing them failed or never finished.
+func HandleGithubWebhook(w http.ResponseWriter, r *http.Request) {
+  payload, err := github.ValidatePayload(r, []byte(githubSecretKey))
+  if err != nil {
+    log.Printf("ValidatePayload: ERROR: %v\n", err);
+    http.Error(w, "invalid signature", http.StatusUnauthorized)
+    return;
+  }
+
+  event_type := github.WebHookType(r)
+  if event_type == "ping" {
+    fmt.Fprint(w, "pong")
+    return
+  }
+
+  fsclient, err := fsresolutions.NewClient(gcpProjectId, gcpFsCollection)
+  if err != nil {
+    log.Printf("fsresolutions.NewClient: ERROR: %v\n", err);
+    w.WriteHeader(http.StatusInternalServerError)
     return
   }
+  defer fsclient.Close()
+
+  delivery := &fsresolutions.WebhookDelivery{
+    Event: event_type,
+    Payload: payload,
+    ReceivedTime: time.Now(),
+  }
+
+  // Recording the delivery claims it, so that concurrent redeliveries don't
+  // both process it.
+  id := github.DeliveryID(r)
+  if id != "" {
+    claimed, err := fsclient.ClaimWebhookDelivery(id, delivery,
+        func(previous *fsresolutions.WebhookDelivery) bool {
+      return isDeliveryClaimExpired(previous, delivery.ReceivedTime)
+    })
+    if err != nil {
+      // The event is still worth handling, it just can't be deduplicated.
+      log.Printf("ClaimWebhookDelivery: ERROR: %v\n", err);
+    } else if !claimed {
+      log.Printf("delivery %s is a duplicate\n", id);
+      fmt.Fprint(w, "already received")
+      return
+    }
+  }
+  w.WriteHeader(processDelivery(fsclient, id, delivery))
+}
+
+// Returns true if the request carries REPLAY_SECRET_KEY as its bearer token.
+func isReplayAuthorized(r *http.Request) bool {
+  if replaySecretKey == "" {
+    return false
+  }
+  token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+  return subtle.ConstantTimeCompare([]byte(token), []byte(replaySecretKey)) == 1
+}
+
+// Entry point to reprocess a stored delivery, given as the "delivery" query
+// parameter. Stored deliveries were validated when they arrived, so instead of
+// a github signature this requires REPLAY_SECRET_KEY as the bearer token.
+func HandleReplayDelivery(w http.ResponseWriter, r *http.Request) {
+  if !isReplayAuthorized(r) {
+    http.Error(w, "unauthorized", http.StatusUnauthorized)
+    return
+  }
+
+  id := r.URL.Query().Get("delivery")
+  if id == "" {
+    http.Error(w, "missing delivery parameter", http.StatusBadRequest)
+    return
+  }
+
+  fsclient, err := fsresolutions.NewClient(gcpProjectId, gcpFsCollection)
+  if err != nil {
+    log.Printf("fsresolutions.NewClient: ERROR: %v\n", err);
+    w.WriteHeader(http.StatusInternalServerError)
+    return
+  }
+  defer fsclient.Close()
+
+  delivery, err := fsclient.LoadWebhookDelivery(id)
+  if err != nil {
+    log.Printf("LoadWebhookDelivery: ERROR: %v\n", err);
+    w.WriteHeader(http.StatusInternalServerError)
+    return
+  }
+  if delivery == nil {
+    http.Error(w, "unknown delivery", http.StatusNotFound)
+    return
+  }
+
+  log.Printf("replaying delivery %s (%s)\n", id, delivery.Event);
+  w.WriteHeader(processDelivery(fsclient, id, delivery))
 }
@@ -0,0 +1,31 @@
+package webhook_handler_cf
+
+import (
+  "testing"
+  "time"
+
+  "github.com/chromium-helper/csswg-resolutions/fsresolutions"
+)
+
+func TestIsDeliveryClaimExpired(t *testing.T) {
+  now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+  recent := now.Add(-time.Minute)
+  stale := now.Add(-kDeliveryClaimTimeout - time.Second)
+
+  tests := []struct {
+    name string
+    previous fsresolutions.WebhookDelivery
+    want bool
+  }{
+    {"being processed", fsresolutions.WebhookDelivery{ ReceivedTime: recent }, false},
+    {"crashed or timed out", fsresolutions.WebhookDelivery{ ReceivedTime: stale }, true},
+    {"failed", fsresolutions.WebhookDelivery{ ReceivedTime: recent, Error: "oops" }, true},
+    {"processed", fsresolutions.WebhookDelivery{ ReceivedTime: recent, ProcessedTime: recent }, false},
+    {"processed long ago", fsresolutions.WebhookDelivery{ ReceivedTime: stale, ProcessedTime: stale }, false},
+  }
+  for _, test := range tests {
+    if got := isDeliveryClaimExpired(&test.previous, now); got != test.want {
+      t.Errorf("%s: isDeliveryClaimExpired is %v, want %v", test.name, got, test.want)
+    }
+  }
+}
@@ -0,0 +1,113 @@
+package monorail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"golang.org/x/oauth2"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Deadline applied to api calls whose context doesn't already have one.
+const DefaultRequestTimeout = 1 * time.Minute
+
+// Creates the token source that requests are authenticated with. Sources like
+// idtoken.NewTokenSource and google.DefaultTokenSource cache their tokens, so
+// after the server rejects a token a new source is created to get a fresh one.
+type TokenSourceFactory func() (oauth2.TokenSource, error)
+
+// Caches the token from the base source until it expires, and allows the
+// cached token and base source to be dropped when the server rejects the
+// token.
+type refreshingTokenSource struct {
+	mu       sync.Mutex
+	new_base TokenSourceFactory
+	base     oauth2.TokenSource
+	token    *oauth2.Token
+}
+
+func newRefreshingTokenSource(new_base TokenSourceFactory) *refreshingTokenSource {
+	return &refreshingTokenSource{new_base: new_base}
+}
+
+func (s *refreshingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+	if s.base == nil {
+		base, err := s.new_base()
+		if err != nil {
+			return nil, fmt.Errorf("creating token source: %v", err)
+		}
+		s.base = base
+	}
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+// Drops the cached token and base source, so that the next request gets a new
+// token.
+func (s *refreshingTokenSource) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = nil
+	s.base = nil
+}
+
+func createHttpClient(tokens *refreshingTokenSource) (*http.Client, error) {
+	transport := &oauth2.Transport{
+		Source: tokens,
+		Base:   http.DefaultTransport,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   DefaultRequestTimeout,
+	}, nil
+}
+
+// Sends a request and reads the whole response. If the server responds with
+// 401, the token is refreshed and the request is retried once. tokens may be
+// nil if the client doesn't authenticate (e.g. in tests).
+func doRequest(ctx context.Context, client *http.Client, tokens *refreshingTokenSource,
+	method string, url string, payload []byte) (*http.Response, []byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRequestTimeout)
+		defer cancel()
+	}
+
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+		if err != nil {
+			return nil, nil, fmt.Errorf("http.NewRequest: %v", err)
+		}
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("Accept", "application/json")
+
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, nil, fmt.Errorf("http.Client.Do: %v", err)
+		}
+		result, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("ioutil.ReadAll: %v", err)
+		}
+
+		if response.StatusCode == http.StatusUnauthorized && tokens != nil && attempt == 0 {
+			tokens.invalidate()
+			continue
+		}
+		return response, result, nil
+	}
+}
@@ -0,0 +1,112 @@
+package monorail
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Records the deadline of each request's context.
+type deadlineTransport struct {
+	deadlines []time.Time
+}
+
+func (t *deadlineTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	deadline, _ := request.Context().Deadline()
+	t.deadlines = append(t.deadlines, deadline)
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+// A server that rejects the first unauthorized requests with 401, and a
+// client whose token sources hand out "token-1", "token-2" etc., one per
+// source.
+type authTest struct {
+	url       string
+	client    *http.Client
+	tokens    *refreshingTokenSource
+	transport *deadlineTransport
+	// The Authorization headers the server saw
+	seen []string
+	// How many token sources were created
+	sources int
+}
+
+func newAuthTest(t *testing.T, unauthorized int) *authTest {
+	test := &authTest{transport: &deadlineTransport{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		test.seen = append(test.seen, r.Header.Get("Authorization"))
+		if len(test.seen) <= unauthorized {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	test.url = server.URL
+	test.tokens = newRefreshingTokenSource(func() (oauth2.TokenSource, error) {
+		test.sources++
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: fmt.Sprintf("token-%d", test.sources)}), nil
+	})
+	test.client = &http.Client{Transport: &oauth2.Transport{Source: test.tokens, Base: test.transport}}
+	return test
+}
+
+func TestDoRequestRefreshesRejectedToken(t *testing.T) {
+	test := newAuthTest(t, 1)
+
+	start := time.Now()
+	response, body, err := doRequest(context.Background(), test.client, test.tokens, "POST", test.url, nil)
+	if err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+	if response.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Errorf("doRequest returned %d %q", response.StatusCode, body)
+	}
+	want := []string{"Bearer token-1", "Bearer token-2"}
+	if fmt.Sprint(test.seen) != fmt.Sprint(want) || test.sources != 2 {
+		t.Errorf("server saw %v from %d token sources, want %v from 2", test.seen, test.sources, want)
+	}
+
+	// Both attempts share the default deadline.
+	end := time.Now()
+	deadlines := test.transport.deadlines
+	if len(deadlines) != 2 || !deadlines[0].Equal(deadlines[1]) ||
+		deadlines[0].Before(start.Add(DefaultRequestTimeout)) || deadlines[0].After(end.Add(DefaultRequestTimeout)) {
+		t.Errorf("request deadlines are %v, want one %v after the call", deadlines, DefaultRequestTimeout)
+	}
+}
+
+func TestDoRequestRetriesOnce(t *testing.T) {
+	test := newAuthTest(t, 2)
+
+	response, _, err := doRequest(context.Background(), test.client, test.tokens, "POST", test.url, nil)
+	if err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("doRequest returned %d, want the second 401", response.StatusCode)
+	}
+	if len(test.seen) != 2 || test.sources != 2 {
+		t.Errorf("server saw %v from %d token sources, want two requests", test.seen, test.sources)
+	}
+}
+
+func TestDoRequestKeepsCallerDeadline(t *testing.T) {
+	test := newAuthTest(t, 0)
+
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if _, _, err := doRequest(ctx, test.client, test.tokens, "POST", test.url, nil); err != nil {
+		t.Fatalf("doRequest: %v", err)
+	}
+	if len(test.transport.deadlines) != 1 || !test.transport.deadlines[0].Equal(deadline) {
+		t.Errorf("request deadlines are %v, want %v", test.transport.deadlines, deadline)
+	}
+}
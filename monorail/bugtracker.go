@@ -0,0 +1,25 @@
+package monorail
+
+import "context"
+
+// BugTracker is implemented by the services that can file and update crbugs.
+// IssuesService talks to Monorail, IssueTrackerService talks to the Chromium
+// Issue Tracker (Buganizer).
+type BugTracker interface {
+	CreateIssue(request *CreateIssueRequest) (*Issue, error)
+	ModifyIssue(request *ModifyIssueRequest) error
+	GetIssue(project string, crbug int) (*Issue, error)
+
+	CreateIssueContext(ctx context.Context, request *CreateIssueRequest) (*Issue, error)
+	ModifyIssueContext(ctx context.Context, request *ModifyIssueRequest) error
+	GetIssueContext(ctx context.Context, project string, crbug int) (*Issue, error)
+
+	// Used to validate triage directives before filing.
+	ListComponents(ctx context.Context, project string) ([]string, error)
+	UserExists(ctx context.Context, email string) (bool, error)
+}
+
+var (
+	_ BugTracker = (*IssuesService)(nil)
+	_ BugTracker = (*IssueTrackerService)(nil)
+)
@@ -0,0 +1,205 @@
+package monorail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gRPC status codes, as reported by pRPC in the X-Prpc-Grpc-Code header.
+type Code int
+
+const (
+	CodeOK                Code = 0
+	CodeUnknown           Code = 2
+	CodeInvalidArgument   Code = 3
+	CodeDeadlineExceeded  Code = 4
+	CodeNotFound          Code = 5
+	CodePermissionDenied  Code = 7
+	CodeResourceExhausted Code = 8
+	CodeInternal          Code = 13
+	CodeUnavailable       Code = 14
+	CodeUnauthenticated   Code = 16
+)
+
+func (c Code) String() string {
+	switch c {
+	case CodeOK:
+		return "OK"
+	case CodeUnknown:
+		return "Unknown"
+	case CodeInvalidArgument:
+		return "InvalidArgument"
+	case CodeDeadlineExceeded:
+		return "DeadlineExceeded"
+	case CodeNotFound:
+		return "NotFound"
+	case CodePermissionDenied:
+		return "PermissionDenied"
+	case CodeResourceExhausted:
+		return "ResourceExhausted"
+	case CodeInternal:
+		return "Internal"
+	case CodeUnavailable:
+		return "Unavailable"
+	case CodeUnauthenticated:
+		return "Unauthenticated"
+	}
+	return fmt.Sprintf("Code(%d)", int(c))
+}
+
+// Error is returned for api calls that the server rejected. Use errors.Is with
+// ErrNotFound etc. to check the kind of error.
+type Error struct {
+	Code       Code
+	HttpStatus int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (http %d): %s", e.Code, e.HttpStatus, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Whether the request may succeed if retried.
+func (e *Error) Transient() bool {
+	switch e.Code {
+	case CodeUnavailable, CodeDeadlineExceeded, CodeResourceExhausted, CodeInternal:
+		return true
+	}
+	return false
+}
+
+var (
+	ErrInvalidArgument  = &Error{Code: CodeInvalidArgument}
+	ErrNotFound         = &Error{Code: CodeNotFound}
+	ErrPermissionDenied = &Error{Code: CodePermissionDenied}
+	ErrUnavailable      = &Error{Code: CodeUnavailable}
+)
+
+// Maps an http status to the closest code, for servers (or proxies) that don't
+// report a grpc code.
+func codeFromHttpStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusTooManyRequests:
+		return CodeResourceExhausted
+	case http.StatusInternalServerError:
+		return CodeInternal
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeDeadlineExceeded
+	}
+	return CodeUnknown
+}
+
+// Creates an error from a failed response, using the X-Prpc-Grpc-Code header if
+// present.
+func newError(response *http.Response, body []byte) *Error {
+	code := codeFromHttpStatus(response.StatusCode)
+	if header := response.Header.Get("X-Prpc-Grpc-Code"); header != "" {
+		if n, err := strconv.Atoi(header); err == nil {
+			code = Code(n)
+		}
+	}
+	return &Error{
+		Code:       code,
+		HttpStatus: response.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+	}
+}
+
+// Returned when a request could not be sent or its response could not be read.
+// The server may or may not have acted on the request.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// Whether a failed call should be retried. Reads are retried on any transient
+// error. Writes are only retried when the server turned them away: after a
+// timeout or an internal error the write may have gone through, and retrying
+// could e.g. file the same crbug twice.
+func retryable(err error, idempotent bool) bool {
+	var api_err *Error
+	if errors.As(err, &api_err) {
+		if idempotent {
+			return api_err.Transient()
+		}
+		return api_err.Code == CodeUnavailable && api_err.HttpStatus == http.StatusServiceUnavailable
+	}
+	var transport_err *transportError
+	return idempotent && errors.As(err, &transport_err)
+}
+
+type RetryPolicy struct {
+	// Total number of attempts, including the first one.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// Exponential backoff with full jitter.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	backoff := p.BaseDelay << uint(attempt)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// Calls fn until it succeeds, returns an error that isn't retryable, or runs
+// out of attempts. idempotent says whether fn may safely run more than once.
+func (p *RetryPolicy) do(ctx context.Context, idempotent bool, fn func() error) error {
+	if p == nil {
+		p = DefaultRetryPolicy
+	}
+
+	var err error
+	for attempt := 0; attempt < p.MaxAttempts; attempt++ {
+		err = fn()
+		if err == nil || !retryable(err, idempotent) {
+			return err
+		}
+		if attempt+1 == p.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (gave up retrying: %v)", err, ctx.Err())
+		case <-time.After(p.delay(attempt)):
+		}
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package monorail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var zeroDelayPolicy = &RetryPolicy{MaxAttempts: 3}
+
+func TestCodeString(t *testing.T) {
+	tests := []struct {
+		code Code
+		want string
+	}{
+		{CodeOK, "OK"},
+		{CodeUnknown, "Unknown"},
+		{CodeNotFound, "NotFound"},
+		{CodeUnavailable, "Unavailable"},
+		{Code(1), "Code(1)"},
+	}
+	for _, test := range tests {
+		if got := test.code.String(); got != test.want {
+			t.Errorf("Code(%d).String() is %q, want %q", int(test.code), got, test.want)
+		}
+	}
+}
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header string
+		want   Code
+	}{
+		{"header", http.StatusBadRequest, "5", CodeNotFound},
+		{"header overrides status", http.StatusServiceUnavailable, "3", CodeInvalidArgument},
+		{"no header", http.StatusForbidden, "", CodePermissionDenied},
+		{"bad header", http.StatusGatewayTimeout, "x", CodeDeadlineExceeded},
+		{"unmapped status", http.StatusTeapot, "", CodeUnknown},
+	}
+	for _, test := range tests {
+		response := &http.Response{StatusCode: test.status, Header: http.Header{}}
+		if test.header != "" {
+			response.Header.Set("X-Prpc-Grpc-Code", test.header)
+		}
+		err := newError(response, []byte(" no such issue\n"))
+		if err.Code != test.want || err.HttpStatus != test.status || err.Message != "no such issue" {
+			t.Errorf("%s: newError is %+v, want code %s", test.name, err, test.want)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	transport := &transportError{errors.New("connection reset")}
+	tests := []struct {
+		name       string
+		err        error
+		idempotent bool
+		want       bool
+	}{
+		{"read unavailable", &Error{Code: CodeUnavailable, HttpStatus: 503}, true, true},
+		{"read internal", &Error{Code: CodeInternal, HttpStatus: 500}, true, true},
+		{"read deadline", &Error{Code: CodeDeadlineExceeded, HttpStatus: 504}, true, true},
+		{"read not found", &Error{Code: CodeNotFound, HttpStatus: 404}, true, false},
+		{"read transport", transport, true, true},
+		{"wrapped read", fmt.Errorf("invokeApi: %w", &Error{Code: CodeUnavailable}), true, true},
+		{"write unavailable", &Error{Code: CodeUnavailable, HttpStatus: 503}, false, true},
+		{"write unavailable from a proxy", &Error{Code: CodeUnavailable, HttpStatus: 502}, false, false},
+		{"write internal", &Error{Code: CodeInternal, HttpStatus: 500}, false, false},
+		{"write deadline", &Error{Code: CodeDeadlineExceeded, HttpStatus: 504}, false, false},
+		{"write transport", transport, false, false},
+		{"other", errors.New("missing prefix"), true, false},
+	}
+	for _, test := range tests {
+		if got := retryable(test.err, test.idempotent); got != test.want {
+			t.Errorf("%s: retryable is %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	unavailable := &Error{Code: CodeUnavailable, HttpStatus: 503}
+	tests := []struct {
+		name         string
+		errs         []error
+		want_calls   int
+		want_success bool
+	}{
+		{"success", []error{nil}, 1, true},
+		{"retried", []error{unavailable, unavailable, nil}, 3, true},
+		{"out of attempts", []error{unavailable, unavailable, unavailable, nil}, 3, false},
+		{"not retryable", []error{ErrNotFound, nil}, 1, false},
+	}
+	for _, test := range tests {
+		calls := 0
+		err := zeroDelayPolicy.do(context.Background(), false, func() error {
+			calls++
+			return test.errs[calls-1]
+		})
+		if calls != test.want_calls || (err == nil) != test.want_success {
+			t.Errorf("%s: %d calls returned %v, want %d calls", test.name, calls, err, test.want_calls)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt := 0; attempt < 10; attempt++ {
+		limit := policy.BaseDelay << uint(attempt)
+		if limit > policy.MaxDelay {
+			limit = policy.MaxDelay
+		}
+		for i := 0; i < 20; i++ {
+			if delay := policy.delay(attempt); delay < 0 || delay > limit {
+				t.Errorf("delay(%d) is %v, want at most %v", attempt, delay, limit)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policy := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	calls := 0
+	err := policy.do(ctx, true, func() error {
+		calls++
+		return ErrUnavailable
+	})
+	if calls != 1 || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("%d calls returned %v, want to give up after one", calls, err)
+	}
+}
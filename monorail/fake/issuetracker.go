@@ -0,0 +1,151 @@
+// Package fake contains in-memory fakes of the bug tracker servers, for use in
+// tests.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IssueTrackerServer is a fake of the subset of the Issue Tracker REST API
+// that monorail.IssueTrackerService uses. Point the service's ApiBase at URL
+// and its HttpClient at Client().
+type IssueTrackerServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	nextId int64
+	// Issues keyed by id. Each issue is the raw json object, as the API would
+	// return it.
+	Issues map[int64]map[string]interface{}
+	// Modify requests received, keyed by issue id.
+	Modifications map[int64][]map[string]interface{}
+}
+
+func NewIssueTrackerServer() *IssueTrackerServer {
+	s := &IssueTrackerServer{
+		nextId:        1000000,
+		Issues:        make(map[int64]map[string]interface{}),
+		Modifications: make(map[int64][]map[string]interface{}),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Adds an issue with the given state (e.g. {"status": "FIXED"}) and returns
+// its id.
+func (s *IssueTrackerServer) AddIssue(state map[string]interface{}) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextId
+	s.nextId++
+	s.Issues[id] = map[string]interface{}{
+		"issueId":    strconv.FormatInt(id, 10),
+		"issueState": state,
+	}
+	return id
+}
+
+func (s *IssueTrackerServer) writeJson(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func (s *IssueTrackerServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var request map[string]interface{}
+	if len(body) != 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case r.Method == "POST" && path == "issues":
+		s.createIssue(w, request)
+	case r.Method == "POST" && strings.HasPrefix(path, "issues/") && strings.HasSuffix(path, ":modify"):
+		s.modifyIssue(w, strings.TrimSuffix(strings.TrimPrefix(path, "issues/"), ":modify"), request)
+	case r.Method == "GET" && strings.HasPrefix(path, "issues/"):
+		s.getIssue(w, strings.TrimPrefix(path, "issues/"))
+	default:
+		http.Error(w, fmt.Sprintf("unexpected %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+	}
+}
+
+func (s *IssueTrackerServer) createIssue(w http.ResponseWriter, request map[string]interface{}) {
+	state, ok := request["issueState"].(map[string]interface{})
+	if !ok {
+		http.Error(w, "missing issueState", http.StatusBadRequest)
+		return
+	}
+	if _, ok := state["componentId"]; !ok {
+		http.Error(w, "missing componentId", http.StatusBadRequest)
+		return
+	}
+
+	id := s.nextId
+	s.nextId++
+	issue := map[string]interface{}{
+		"issueId":    strconv.FormatInt(id, 10),
+		"issueState": state,
+	}
+	if comment, ok := request["issueComment"]; ok {
+		issue["issueComment"] = comment
+	}
+	s.Issues[id] = issue
+	s.writeJson(w, issue)
+}
+
+func (s *IssueTrackerServer) lookup(w http.ResponseWriter, id_string string) (int64, map[string]interface{}) {
+	id, err := strconv.ParseInt(id_string, 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, nil
+	}
+	issue, ok := s.Issues[id]
+	if !ok {
+		http.Error(w, fmt.Sprintf("issue %d not found", id), http.StatusNotFound)
+		return 0, nil
+	}
+	return id, issue
+}
+
+func (s *IssueTrackerServer) modifyIssue(w http.ResponseWriter, id_string string, request map[string]interface{}) {
+	id, issue := s.lookup(w, id_string)
+	if issue == nil {
+		return
+	}
+	s.Modifications[id] = append(s.Modifications[id], request)
+
+	if add, ok := request["add"].(map[string]interface{}); ok {
+		state := issue["issueState"].(map[string]interface{})
+		for key, value := range add {
+			state[key] = value
+		}
+	}
+	s.writeJson(w, issue)
+}
+
+func (s *IssueTrackerServer) getIssue(w http.ResponseWriter, id_string string) {
+	_, issue := s.lookup(w, id_string)
+	if issue == nil {
+		return
+	}
+	s.writeJson(w, issue)
+}
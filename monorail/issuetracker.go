@@ -0,0 +1,297 @@
+package monorail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	IssueTrackerApiBase = "https://issuetracker.googleapis.com/v1"
+	IssueTrackerScope   = "https://www.googleapis.com/auth/buganizer"
+)
+
+// Issue Tracker statuses mapped to the Monorail status names that the rest of
+// the code understands.
+var issueTrackerStatuses = map[string]string{
+	"NEW":               "Untriaged",
+	"ASSIGNED":          "Assigned",
+	"ACCEPTED":          "Started",
+	"FIXED":             "Fixed",
+	"VERIFIED":          "Verified",
+	"NOT_REPRODUCIBLE":  "WontFix",
+	"INTENDED_BEHAVIOR": "WontFix",
+	"OBSOLETE":          "WontFix",
+	"INFEASIBLE":        "WontFix",
+	"DUPLICATE":         "Duplicate",
+}
+
+type IssueTrackerService struct {
+	HttpClient *http.Client
+	ApiBase    string
+	// Maps Monorail component names (e.g. "Blink>Layout") to Issue Tracker
+	// component ids.
+	ComponentIds map[string]int64
+	// Retries for transient errors. DefaultRetryPolicy is used if nil.
+	RetryPolicy *RetryPolicy
+
+	tokens *refreshingTokenSource
+}
+
+type issueTrackerUser struct {
+	EmailAddress string `json:"emailAddress"`
+}
+
+type issueTrackerIssueState struct {
+	ComponentId      int64               `json:"componentId,string,omitempty"`
+	Type             string              `json:"type,omitempty"`
+	Status           string              `json:"status,omitempty"`
+	Priority         string              `json:"priority,omitempty"`
+	Title            string              `json:"title,omitempty"`
+	Assignee         *issueTrackerUser   `json:"assignee,omitempty"`
+	Ccs              []*issueTrackerUser `json:"ccs,omitempty"`
+	CanonicalIssueId int64               `json:"canonicalIssueId,string,omitempty"`
+}
+
+type issueTrackerComment struct {
+	Comment string `json:"comment"`
+}
+
+type issueTrackerIssue struct {
+	IssueId      int64                   `json:"issueId,string,omitempty"`
+	IssueState   *issueTrackerIssueState `json:"issueState"`
+	IssueComment *issueTrackerComment    `json:"issueComment,omitempty"`
+	ResolvedTime time.Time               `json:"resolvedTime,omitempty"`
+}
+
+// Loads a json file of the form { "Blink>Layout": 1456332, ... }
+func LoadComponentIds(path string) (map[string]int64, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile: %v", err)
+	}
+
+	var component_ids map[string]int64
+	if err := json.Unmarshal(contents, &component_ids); err != nil {
+		return nil, fmt.Errorf("Unmarshal: %v", err)
+	}
+	return component_ids, nil
+}
+
+func NewIssueTrackerService(ctx context.Context, new_token_source TokenSourceFactory,
+	component_ids map[string]int64) (*IssueTrackerService, error) {
+	tokens := newRefreshingTokenSource(new_token_source)
+	http_client, err := createHttpClient(tokens)
+	if err != nil {
+		return nil, fmt.Errorf("createHttpClient: %v", err)
+	}
+
+	return &IssueTrackerService{
+		HttpClient:   http_client,
+		ApiBase:      IssueTrackerApiBase,
+		ComponentIds: component_ids,
+		tokens:       tokens,
+	}, nil
+}
+
+func (s *IssueTrackerService) invokeApi(ctx context.Context, method string, path string, payload []byte) ([]byte, error) {
+	url := fmt.Sprintf("%s/%s", s.ApiBase, path)
+
+	var result []byte
+	err := s.RetryPolicy.do(ctx, method == "GET", func() error {
+		response, body, err := doRequest(ctx, s.HttpClient, s.tokens, method, url, payload)
+		if err != nil {
+			return &transportError{err}
+		}
+
+		if response.StatusCode != 200 {
+			return newError(response, body)
+		}
+		result = body
+		return nil
+	})
+	return result, err
+}
+
+// Returns the id of the one component that Issue Tracker issues live in.
+// Rather than silently dropping the rest, more than one component is an error.
+func (s *IssueTrackerService) singleComponentId(components []string) (int64, error) {
+	if len(components) != 1 {
+		return 0, &Error{
+			Code:    CodeInvalidArgument,
+			Message: fmt.Sprintf("issue tracker issues have exactly one component, got %d", len(components)),
+		}
+	}
+	return s.componentId(components[0])
+}
+
+func (s *IssueTrackerService) componentId(component string) (int64, error) {
+	id, ok := s.ComponentIds[component]
+	if !ok {
+		return 0, &Error{
+			Code:    CodeInvalidArgument,
+			Message: fmt.Sprintf("no issue tracker component id for %s", component),
+		}
+	}
+	return id, nil
+}
+
+func toIssueTrackerUsers(emails []string) []*issueTrackerUser {
+	var users []*issueTrackerUser
+	for _, email := range emails {
+		users = append(users, &issueTrackerUser{EmailAddress: email})
+	}
+	return users
+}
+
+func (s *IssueTrackerService) CreateIssue(request *CreateIssueRequest) (*Issue, error) {
+	return s.CreateIssueContext(context.Background(), request)
+}
+
+func (s *IssueTrackerService) CreateIssueContext(ctx context.Context, request *CreateIssueRequest) (*Issue, error) {
+	component_id, err := s.singleComponentId(request.Components)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &issueTrackerIssueState{
+		ComponentId: component_id,
+		Type:        "TASK",
+		Status:      "NEW",
+		Priority:    "P" + request.priority(),
+		Title:       request.Summary,
+		Ccs:         toIssueTrackerUsers(request.CcList),
+	}
+	if request.Owner != "" {
+		state.Assignee = &issueTrackerUser{EmailAddress: request.Owner}
+		state.Status = "ASSIGNED"
+	}
+
+	json_request, err := json.Marshal(&issueTrackerIssue{
+		IssueState:   state,
+		IssueComment: &issueTrackerComment{Comment: request.Description},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Marshal: %v", err)
+	}
+
+	result, err := s.invokeApi(ctx, "POST", "issues", json_request)
+	if err != nil {
+		return nil, fmt.Errorf("invokeApi: %w", err)
+	}
+
+	var issue *issueTrackerIssue
+	if err := json.Unmarshal(result, &issue); err != nil {
+		return nil, fmt.Errorf("Unmarshal: %v", err)
+	}
+	return &Issue{Id: int(issue.IssueId)}, nil
+}
+
+func (s *IssueTrackerService) ModifyIssue(request *ModifyIssueRequest) error {
+	return s.ModifyIssueContext(context.Background(), request)
+}
+
+func (s *IssueTrackerService) ModifyIssueContext(ctx context.Context, request *ModifyIssueRequest) error {
+	type WireRequestType struct {
+		Add          *issueTrackerIssueState `json:"add,omitempty"`
+		AddMask      string                  `json:"addMask,omitempty"`
+		IssueComment *issueTrackerComment    `json:"issueComment,omitempty"`
+	}
+
+	var add_mask []string
+	add := &issueTrackerIssueState{}
+	if request.Owner != "" {
+		add.Assignee = &issueTrackerUser{EmailAddress: request.Owner}
+		add.Status = "ASSIGNED"
+		add_mask = append(add_mask, "assignee", "status")
+	}
+	if len(request.CcList) != 0 {
+		add.Ccs = toIssueTrackerUsers(request.CcList)
+		add_mask = append(add_mask, "ccs")
+	}
+	if len(request.Components) != 0 {
+		component_id, err := s.singleComponentId(request.Components)
+		if err != nil {
+			return err
+		}
+		add.ComponentId = component_id
+		add_mask = append(add_mask, "componentId")
+	}
+
+	wireRequest := &WireRequestType{}
+	if len(add_mask) != 0 {
+		wireRequest.Add = add
+		wireRequest.AddMask = strings.Join(add_mask, ",")
+	}
+	if request.Comment != "" {
+		wireRequest.IssueComment = &issueTrackerComment{Comment: request.Comment}
+	}
+
+	json_request, err := json.Marshal(wireRequest)
+	if err != nil {
+		return fmt.Errorf("Marshal: %v", err)
+	}
+
+	_, err = s.invokeApi(ctx, "POST", fmt.Sprintf("issues/%d:modify", request.Crbug), json_request)
+	if err != nil {
+		return fmt.Errorf("invokeApi: %w", err)
+	}
+	return nil
+}
+
+func (s *IssueTrackerService) GetIssue(project string, crbug int) (*Issue, error) {
+	return s.GetIssueContext(context.Background(), project, crbug)
+}
+
+// The project is ignored, since Issue Tracker ids are global.
+func (s *IssueTrackerService) GetIssueContext(ctx context.Context, project string, crbug int) (*Issue, error) {
+	result, err := s.invokeApi(ctx, "GET", fmt.Sprintf("issues/%d", crbug), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invokeApi: %w", err)
+	}
+
+	var wire_issue *issueTrackerIssue
+	if err := json.Unmarshal(result, &wire_issue); err != nil {
+		return nil, fmt.Errorf("Unmarshal: %v", err)
+	}
+	if wire_issue.IssueState == nil {
+		return nil, fmt.Errorf("issue %d has no issueState", crbug)
+	}
+
+	// TODO: Milestones are custom fields in Issue Tracker, and are not
+	// populated here yet.
+	issue := &Issue{
+		Id:         int(wire_issue.IssueId),
+		Status:     issueTrackerStatuses[wire_issue.IssueState.Status],
+		ClosedTime: wire_issue.ResolvedTime,
+		MergedInto: int(wire_issue.IssueState.CanonicalIssueId),
+	}
+	if issue.Status == "" {
+		issue.Status = wire_issue.IssueState.Status
+	}
+	if wire_issue.IssueState.Assignee != nil {
+		issue.Owner = wire_issue.IssueState.Assignee.EmailAddress
+	}
+	return issue, nil
+}
+
+// Lists the components that have a known issue tracker component id.
+func (s *IssueTrackerService) ListComponents(ctx context.Context, project string) ([]string, error) {
+	var components []string
+	for component := range s.ComponentIds {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	return components, nil
+}
+
+// Issue Tracker has no api to look up users, so every user is assumed to
+// exist. Unknown users are reported by CreateIssue instead.
+func (s *IssueTrackerService) UserExists(ctx context.Context, email string) (bool, error) {
+	return true, nil
+}
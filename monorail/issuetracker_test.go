@@ -0,0 +1,183 @@
+package monorail
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/chromium-helper/csswg-resolutions/monorail/fake"
+)
+
+func newFakeIssueTrackerService(t *testing.T) (*IssueTrackerService, *fake.IssueTrackerServer) {
+	server := fake.NewIssueTrackerServer()
+	t.Cleanup(server.Close)
+	service := &IssueTrackerService{
+		HttpClient: server.Client(),
+		ApiBase:    server.URL,
+		ComponentIds: map[string]int64{
+			"Blink>Layout": 1456332,
+			"Blink>CSS":    1456000,
+		},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 1},
+	}
+	return service, server
+}
+
+func TestIssueTrackerCreateIssue(t *testing.T) {
+	service, server := newFakeIssueTrackerService(t)
+
+	issue, err := service.CreateIssue(&CreateIssueRequest{
+		Project:     "chromium",
+		Summary:     "Implement the resolution",
+		Description: "See the csswg-drafts issue",
+		Owner:       "owner@chromium.org",
+		CcList:      []string{"a@chromium.org", "b@chromium.org"},
+		Components:  []string{"Blink>Layout"},
+	})
+	if err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	created, ok := server.Issues[int64(issue.Id)]
+	if !ok {
+		t.Fatalf("CreateIssue returned id %d, which the server doesn't have", issue.Id)
+	}
+	state := created["issueState"].(map[string]interface{})
+	want := map[string]interface{}{
+		"componentId": "1456332",
+		"type":        "TASK",
+		"status":      "ASSIGNED",
+		"priority":    "P2",
+		"title":       "Implement the resolution",
+		"assignee":    map[string]interface{}{"emailAddress": "owner@chromium.org"},
+		"ccs": []interface{}{
+			map[string]interface{}{"emailAddress": "a@chromium.org"},
+			map[string]interface{}{"emailAddress": "b@chromium.org"},
+		},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("issueState is %v, want %v", state, want)
+	}
+	comment := created["issueComment"].(map[string]interface{})
+	if comment["comment"] != "See the csswg-drafts issue" {
+		t.Errorf("issueComment is %v, want the description", comment)
+	}
+}
+
+func TestIssueTrackerCreateIssueUnassigned(t *testing.T) {
+	service, server := newFakeIssueTrackerService(t)
+
+	issue, err := service.CreateIssue(&CreateIssueRequest{
+		Summary:    "Implement the resolution",
+		Components: []string{"Blink>CSS"},
+		Priority:   "1",
+	})
+	if err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+	state := server.Issues[int64(issue.Id)]["issueState"].(map[string]interface{})
+	if state["status"] != "NEW" || state["priority"] != "P1" || state["assignee"] != nil {
+		t.Errorf("issueState is %v, want a NEW P1 issue without assignee", state)
+	}
+}
+
+func TestIssueTrackerCreateIssueComponents(t *testing.T) {
+	service, server := newFakeIssueTrackerService(t)
+
+	tests := []struct {
+		name       string
+		components []string
+	}{
+		{"no component", nil},
+		{"unknown component", []string{"Blink>Unknown"}},
+		{"several components", []string{"Blink>Layout", "Blink>CSS"}},
+	}
+	for _, test := range tests {
+		_, err := service.CreateIssue(&CreateIssueRequest{Summary: "x", Components: test.components})
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%s: CreateIssue returned %v, want ErrInvalidArgument", test.name, err)
+		}
+	}
+	if len(server.Issues) != 0 {
+		t.Errorf("server has %d issues, want none", len(server.Issues))
+	}
+}
+
+func TestIssueTrackerModifyIssue(t *testing.T) {
+	service, server := newFakeIssueTrackerService(t)
+	id := server.AddIssue(map[string]interface{}{"status": "NEW", "componentId": "1456000"})
+
+	err := service.ModifyIssue(&ModifyIssueRequest{
+		Project:    "chromium",
+		Crbug:      int(id),
+		Comment:    "Triaged",
+		Owner:      "owner@chromium.org",
+		CcList:     []string{"a@chromium.org"},
+		Components: []string{"Blink>Layout"},
+	})
+	if err != nil {
+		t.Fatalf("ModifyIssue: %v", err)
+	}
+
+	modifications := server.Modifications[id]
+	if len(modifications) != 1 {
+		t.Fatalf("server got %d modifications, want 1", len(modifications))
+	}
+	modification := modifications[0]
+	if modification["addMask"] != "assignee,status,ccs,componentId" {
+		t.Errorf("addMask is %v", modification["addMask"])
+	}
+	want_add := map[string]interface{}{
+		"componentId": "1456332",
+		"status":      "ASSIGNED",
+		"assignee":    map[string]interface{}{"emailAddress": "owner@chromium.org"},
+		"ccs":         []interface{}{map[string]interface{}{"emailAddress": "a@chromium.org"}},
+	}
+	if !reflect.DeepEqual(modification["add"], want_add) {
+		t.Errorf("add is %v, want %v", modification["add"], want_add)
+	}
+	if comment := modification["issueComment"].(map[string]interface{}); comment["comment"] != "Triaged" {
+		t.Errorf("issueComment is %v, want Triaged", comment)
+	}
+}
+
+func TestIssueTrackerModifyIssueCommentOnly(t *testing.T) {
+	service, server := newFakeIssueTrackerService(t)
+	id := server.AddIssue(map[string]interface{}{"status": "NEW"})
+
+	if err := service.ModifyIssue(&ModifyIssueRequest{Crbug: int(id), Comment: "Ping"}); err != nil {
+		t.Fatalf("ModifyIssue: %v", err)
+	}
+	modification := server.Modifications[id][0]
+	if _, ok := modification["add"]; ok {
+		t.Errorf("modification %v changes fields, want only a comment", modification)
+	}
+
+	err := service.ModifyIssue(&ModifyIssueRequest{Crbug: int(id), Components: []string{"Blink>Layout", "Blink>CSS"}})
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("ModifyIssue with several components returned %v, want ErrInvalidArgument", err)
+	}
+}
+
+func TestIssueTrackerGetIssue(t *testing.T) {
+	service, server := newFakeIssueTrackerService(t)
+	id := server.AddIssue(map[string]interface{}{
+		"status":           "DUPLICATE",
+		"assignee":         map[string]interface{}{"emailAddress": "owner@chromium.org"},
+		"canonicalIssueId": "1234",
+	})
+
+	issue, err := service.GetIssue("chromium", int(id))
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	want := &Issue{Id: int(id), Status: "Duplicate", Owner: "owner@chromium.org", MergedInto: 1234}
+	if !reflect.DeepEqual(issue, want) {
+		t.Errorf("GetIssue returned %+v, want %+v", issue, want)
+	}
+
+	_, err = service.GetIssue("chromium", 1)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetIssue of a missing issue returned %v, want ErrNotFound", err)
+	}
+}
@@ -4,9 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"golang.org/x/oauth2"
-	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -14,9 +13,12 @@ import (
 )
 
 type IssuesService struct {
-	Token      *oauth2.Token
 	HttpClient *http.Client
 	ApiBase    string
+	// Retries for transient errors. DefaultRetryPolicy is used if nil.
+	RetryPolicy *RetryPolicy
+
+	tokens *refreshingTokenSource
 }
 
 type monorailIssue struct {
@@ -31,6 +33,12 @@ type monorailIssue struct {
 	Owner struct {
 		User string `json:"user"`
 	} `json:"owner"`
+	Labels []struct {
+		Label string `json:"label"`
+	} `json:"labels"`
+	MergedIntoIssueRef struct {
+		Issue string `json:"issue"`
+	} `json:"mergedIntoIssueRef"`
 	CreatedTime  time.Time `json:"createTime"`
 	ModifiedTime time.Time `json:"modifyTime"`
 	ClosedTime   time.Time `json:"closeTime"`
@@ -39,7 +47,21 @@ type monorailIssue struct {
 
 type Issue struct {
 	Id int
-	// TODO: Populate more stuff I guess?
+
+	// The following are only populated by GetIssue.
+	Status string
+	// Owner email, or empty if there is no owner.
+	Owner string
+	// Milestone from the M-* label, e.g. "114", or empty if not set.
+	Milestone  string
+	ClosedTime time.Time
+	// The issue this was merged into if it is a duplicate, 0 otherwise.
+	MergedInto int
+}
+
+type User struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
 }
 
 func contains(needle string, haystack []string) bool {
@@ -51,17 +73,6 @@ func contains(needle string, haystack []string) bool {
 	return false
 }
 
-func createHttpClient(token_source oauth2.TokenSource) (*http.Client, error) {
-	transport := &oauth2.Transport{
-		Source: token_source,
-		Base:   http.DefaultTransport,
-	}
-	return &http.Client{
-		Transport: transport,
-		Timeout:   1 * time.Minute,
-	}, nil
-}
-
 func GetAudience(target string) (string, error) {
 	if !contains(target, []string{"prod", "dev", "staging"}) {
 		return "", fmt.Errorf("target must be one of prod, dev, staging\n")
@@ -69,7 +80,7 @@ func GetAudience(target string) (string, error) {
 	return fmt.Sprintf("https://monorail-%s.appspot.com", target), nil
 }
 
-func NewIssuesService(ctx context.Context, target string, token_source oauth2.TokenSource) (
+func NewIssuesService(ctx context.Context, target string, new_token_source TokenSourceFactory) (
 	*IssuesService, error) {
 	if !contains(target, []string{"prod", "dev", "staging"}) {
 		return nil, fmt.Errorf("target must be one of prod, dev, staging\n")
@@ -77,51 +88,49 @@ func NewIssuesService(ctx context.Context, target string, token_source oauth2.To
 
 	api_base := fmt.Sprintf("https://api-dot-monorail-%s.appspot.com/prpc", target)
 
-	token, err := token_source.Token()
-	if err != nil {
+	// Fetch a token up front, so that bad credentials are reported here.
+	tokens := newRefreshingTokenSource(new_token_source)
+	if _, err := tokens.Token(); err != nil {
 		return nil, fmt.Errorf("token_source.Token: %v", err)
 	}
 
-	http_client, err := createHttpClient(token_source)
+	http_client, err := createHttpClient(tokens)
 	if err != nil {
 		return nil, fmt.Errorf("createHttpClient: %v", err)
 	}
 
 	return &IssuesService{
-		Token:      token,
 		HttpClient: http_client,
 		ApiBase:    api_base,
+		tokens:     tokens,
 	}, nil
 }
 
-func (s *IssuesService) invokeApi(payload []byte, service string, method string) ([]byte, error) {
-	url := fmt.Sprintf("%s/monorail.v3.%s/%s", s.ApiBase, service, method)
-
-	request, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	if err != nil {
-		return nil, fmt.Errorf("http.NewRequest: %v", err)
-	}
-
-	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s\n", s.Token.AccessToken))
-	request.Header.Add("Content-Type", "application/json")
-	request.Header.Add("Accept", "application/json")
+// pRPC prefixes json responses with this to prevent XSSI.
+var xssiPrefix = []byte(")]}'")
 
-	response, err := s.HttpClient.Do(request)
-	if err != nil {
-		return nil, fmt.Errorf("http.Client.Do: %v", err)
-	}
-	defer response.Body.Close()
+// pRPC calls are all POSTs, so callers say whether the method only reads.
+func (s *IssuesService) invokeApi(ctx context.Context, payload []byte, service string, method string, idempotent bool) ([]byte, error) {
+	url := fmt.Sprintf("%s/monorail.v3.%s/%s", s.ApiBase, service, method)
 
-	result, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("ioutil.ReadAll: %v", err)
-	}
+	var result []byte
+	err := s.RetryPolicy.do(ctx, idempotent, func() error {
+		response, body, err := doRequest(ctx, s.HttpClient, s.tokens, "POST", url, payload)
+		if err != nil {
+			return &transportError{err}
+		}
 
-	if response.StatusCode != 200 {
-		return nil, fmt.Errorf("http response %d (%s)\nbody: %s", response.StatusCode, http.StatusText(response.StatusCode), string(result))
-	}
+		if response.StatusCode != 200 {
+			return newError(response, body)
+		}
 
-	return result[4:], nil
+		if !bytes.HasPrefix(body, xssiPrefix) {
+			return fmt.Errorf("response is missing the XSSI prefix: %.40q", body)
+		}
+		result = body[len(xssiPrefix):]
+		return nil
+	})
+	return result, err
 }
 
 type ModifyIssueRequest struct {
@@ -137,6 +146,10 @@ type ModifyIssueRequest struct {
 }
 
 func (s *IssuesService) ModifyIssue(request *ModifyIssueRequest) error {
+	return s.ModifyIssueContext(context.Background(), request)
+}
+
+func (s *IssuesService) ModifyIssueContext(ctx context.Context, request *ModifyIssueRequest) error {
 	type WireStatusType struct {
 		Status string `json:"status"`
 	}
@@ -220,9 +233,9 @@ func (s *IssuesService) ModifyIssue(request *ModifyIssueRequest) error {
 		return fmt.Errorf("Marshal: %v", err)
 	}
 
-	_, err = s.invokeApi([]byte(json_request), "Issues", "ModifyIssues")
+	_, err = s.invokeApi(ctx, []byte(json_request), "Issues", "ModifyIssues", false)
 	if err != nil {
-		return fmt.Errorf("invokeApi: %v", err)
+		return fmt.Errorf("invokeApi: %w", err)
 	}
 	return nil
 }
@@ -237,9 +250,23 @@ type CreateIssueRequest struct {
 	CcList			[]string
 
 	Components []string
+	// "0" to "3", or empty for the default of "2"
+	Priority string
+}
+
+// Returns the requested priority, or the default.
+func (r *CreateIssueRequest) priority() string {
+	if r.Priority == "" {
+		return "2"
+	}
+	return r.Priority
 }
 
 func (s *IssuesService) CreateIssue(request *CreateIssueRequest) (*Issue, error) {
+	return s.CreateIssueContext(context.Background(), request)
+}
+
+func (s *IssuesService) CreateIssueContext(ctx context.Context, request *CreateIssueRequest) (*Issue, error) {
 	type WireComponentType struct {
 		Component string `json:"component"`
 	}
@@ -308,7 +335,7 @@ func (s *IssuesService) CreateIssue(request *CreateIssueRequest) (*Issue, error)
 			Summary:    request.Summary,
 			Components: components,
 			FieldValues: []*WireFieldValueType{
-				&WireFieldValueType{Field: priField, Value: "2"},
+				&WireFieldValueType{Field: priField, Value: request.priority()},
 				&WireFieldValueType{Field: typeField, Value: "Task"},
 			},
 		},
@@ -320,9 +347,9 @@ func (s *IssuesService) CreateIssue(request *CreateIssueRequest) (*Issue, error)
 		return nil, fmt.Errorf("Marshal: %v", err)
 	}
 
-	result, err := s.invokeApi([]byte(json_request), "Issues", "MakeIssue")
+	result, err := s.invokeApi(ctx, []byte(json_request), "Issues", "MakeIssue", false)
 	if err != nil {
-		return nil, fmt.Errorf("invokeApi: %v", err)
+		return nil, fmt.Errorf("invokeApi: %w", err)
 	}
 
 	var monorail_issue *monorailIssue
@@ -330,10 +357,168 @@ func (s *IssuesService) CreateIssue(request *CreateIssueRequest) (*Issue, error)
 		return nil, fmt.Errorf("Unmarshal: %v", err)
 	}
 
-	name_parts := strings.Split(monorail_issue.Name, "/")
-	id, err := strconv.Atoi(name_parts[len(name_parts)-1])
+	id, err := issueIdFromName(monorail_issue.Name)
 	if err != nil {
-		return nil, fmt.Errorf("Atoi of %s: %v", name_parts[len(name_parts)-1], err)
+		return nil, fmt.Errorf("issueIdFromName: %v", err)
 	}
 	return &Issue{Id: id}, nil
 }
+
+// Parses the trailing id out of a "projects/<project>/issues/<id>" name.
+func issueIdFromName(name string) (int, error) {
+	name_parts := strings.Split(name, "/")
+	id, err := strconv.Atoi(name_parts[len(name_parts)-1])
+	if err != nil {
+		return 0, fmt.Errorf("Atoi of %s: %v", name_parts[len(name_parts)-1], err)
+	}
+	return id, nil
+}
+
+func (s *IssuesService) GetIssue(project string, crbug int) (*Issue, error) {
+	return s.GetIssueContext(context.Background(), project, crbug)
+}
+
+func (s *IssuesService) GetIssueContext(ctx context.Context, project string, crbug int) (*Issue, error) {
+	type WireRequestType struct {
+		Name string `json:"name"`
+	}
+
+	wireRequest := &WireRequestType{
+		Name: fmt.Sprintf("projects/%s/issues/%d", project, crbug),
+	}
+
+	json_request, err := json.Marshal(wireRequest)
+	if err != nil {
+		return nil, fmt.Errorf("Marshal: %v", err)
+	}
+
+	result, err := s.invokeApi(ctx, []byte(json_request), "Issues", "GetIssue", true)
+	if err != nil {
+		return nil, fmt.Errorf("invokeApi: %w", err)
+	}
+
+	var monorail_issue *monorailIssue
+	if err := json.Unmarshal(result, &monorail_issue); err != nil {
+		return nil, fmt.Errorf("Unmarshal: %v", err)
+	}
+
+	id, err := issueIdFromName(monorail_issue.Name)
+	if err != nil {
+		return nil, fmt.Errorf("issueIdFromName: %v", err)
+	}
+
+	issue := &Issue{
+		Id:         id,
+		Status:     monorail_issue.State.Status,
+		ClosedTime: monorail_issue.ClosedTime,
+	}
+	if monorail_issue.Owner.User != "" {
+		user, err := s.GetUserContext(ctx, monorail_issue.Owner.User)
+		if err != nil {
+			return nil, fmt.Errorf("GetUserContext: %w", err)
+		}
+		issue.Owner = user.DisplayName
+	}
+	for _, label := range monorail_issue.Labels {
+		if strings.HasPrefix(label.Label, "M-") {
+			issue.Milestone = label.Label[len("M-"):]
+		}
+	}
+	if monorail_issue.MergedIntoIssueRef.Issue != "" {
+		issue.MergedInto, err = issueIdFromName(monorail_issue.MergedIntoIssueRef.Issue)
+		if err != nil {
+			return nil, fmt.Errorf("issueIdFromName: %v", err)
+		}
+	}
+	return issue, nil
+}
+
+// Gets a user by resource name ("users/1234") or email ("users/foo@chromium.org").
+func (s *IssuesService) GetUser(name string) (*User, error) {
+	return s.GetUserContext(context.Background(), name)
+}
+
+func (s *IssuesService) GetUserContext(ctx context.Context, name string) (*User, error) {
+	type WireRequestType struct {
+		Name string `json:"name"`
+	}
+
+	json_request, err := json.Marshal(&WireRequestType{Name: name})
+	if err != nil {
+		return nil, fmt.Errorf("Marshal: %v", err)
+	}
+
+	result, err := s.invokeApi(ctx, []byte(json_request), "Users", "GetUser", true)
+	if err != nil {
+		return nil, fmt.Errorf("invokeApi: %w", err)
+	}
+
+	var user *User
+	if err := json.Unmarshal(result, &user); err != nil {
+		return nil, fmt.Errorf("Unmarshal: %v", err)
+	}
+	return user, nil
+}
+
+// Lists the values (e.g. "Blink>Layout") of all the active components.
+func (s *IssuesService) ListComponents(ctx context.Context, project string) ([]string, error) {
+	type WireRequestType struct {
+		Parent    string `json:"parent"`
+		PageSize  int    `json:"pageSize"`
+		PageToken string `json:"pageToken,omitempty"`
+	}
+
+	type WireResponseType struct {
+		ComponentDefs []struct {
+			Value string `json:"value"`
+			State string `json:"state"`
+		} `json:"componentDefs"`
+		NextPageToken string `json:"nextPageToken"`
+	}
+
+	wireRequest := &WireRequestType{
+		Parent:   fmt.Sprintf("projects/%s", project),
+		PageSize: 1000,
+	}
+
+	var components []string
+	for {
+		json_request, err := json.Marshal(wireRequest)
+		if err != nil {
+			return nil, fmt.Errorf("Marshal: %v", err)
+		}
+
+		result, err := s.invokeApi(ctx, []byte(json_request), "Projects", "ListComponentDefs", true)
+		if err != nil {
+			return nil, fmt.Errorf("invokeApi: %w", err)
+		}
+
+		var response WireResponseType
+		if err := json.Unmarshal(result, &response); err != nil {
+			return nil, fmt.Errorf("Unmarshal: %v", err)
+		}
+		for _, def := range response.ComponentDefs {
+			if def.State == "DEPRECATED" {
+				continue
+			}
+			components = append(components, def.Value)
+		}
+
+		if response.NextPageToken == "" {
+			break
+		}
+		wireRequest.PageToken = response.NextPageToken
+	}
+	return components, nil
+}
+
+func (s *IssuesService) UserExists(ctx context.Context, email string) (bool, error) {
+	_, err := s.GetUserContext(ctx, fmt.Sprintf("users/%s", email))
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,84 @@
+package monorail
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// A canned pRPC response.
+type prpcResponse struct {
+	status int
+	// X-Prpc-Grpc-Code, if any
+	code string
+	body string
+}
+
+// Returns a service for a server that answers each request with the next
+// response, repeating the last one, and a count of the requests.
+func newPrpcService(t *testing.T, responses ...prpcResponse) (*IssuesService, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := responses[len(responses)-1]
+		if requests < len(responses) {
+			response = responses[requests]
+		}
+		requests++
+		if response.code != "" {
+			w.Header().Set("X-Prpc-Grpc-Code", response.code)
+		}
+		w.WriteHeader(response.status)
+		w.Write([]byte(response.body))
+	}))
+	t.Cleanup(server.Close)
+
+	service := &IssuesService{
+		HttpClient:  server.Client(),
+		ApiBase:     server.URL,
+		RetryPolicy: zeroDelayPolicy,
+	}
+	return service, &requests
+}
+
+func TestInvokeApi(t *testing.T) {
+	ok := prpcResponse{200, "0", ")]}'\n{\"name\":\"users/1\"}"}
+	unavailable := prpcResponse{503, "14", "try later"}
+	internal := prpcResponse{500, "13", "oops"}
+	tests := []struct {
+		name          string
+		responses     []prpcResponse
+		idempotent    bool
+		want_requests int
+		// Whether the call fails, and if so with what kind of Error, if any
+		want_fail bool
+		want_err  error
+	}{
+		{"ok", []prpcResponse{ok}, true, 1, false, nil},
+		{"missing xssi prefix", []prpcResponse{{200, "0", "{\"name\":\"users/1\"}"}}, true, 1, true, nil},
+		{"grpc code in header", []prpcResponse{{400, "5", "no such user"}}, true, 1, true, ErrNotFound},
+		{"read retried", []prpcResponse{internal, unavailable, ok}, true, 3, false, nil},
+		{"read out of attempts", []prpcResponse{internal}, true, 3, true, &Error{Code: CodeInternal}},
+		{"write retried when unavailable", []prpcResponse{unavailable, ok}, false, 2, false, nil},
+		{"write not retried after internal error", []prpcResponse{internal, ok}, false, 1, true, &Error{Code: CodeInternal}},
+		{"write not retried after grpc unavailable without 503", []prpcResponse{{500, "14", "oops"}, ok}, false, 1, true, ErrUnavailable},
+	}
+	for _, test := range tests {
+		service, requests := newPrpcService(t, test.responses...)
+		result, err := service.invokeApi(context.Background(), []byte("{}"), "Users", "GetUser", test.idempotent)
+		if *requests != test.want_requests {
+			t.Errorf("%s: sent %d requests, want %d", test.name, *requests, test.want_requests)
+		}
+		switch {
+		case test.want_fail:
+			if err == nil || (test.want_err != nil && !errors.Is(err, test.want_err)) {
+				t.Errorf("%s: invokeApi returned %q, %v, want %v", test.name, result, err, test.want_err)
+			}
+		case err != nil:
+			t.Errorf("%s: invokeApi: %v", test.name, err)
+		case string(result) != "\n{\"name\":\"users/1\"}":
+			t.Errorf("%s: invokeApi returned %q, want the body without the prefix", test.name, result)
+		}
+	}
+}
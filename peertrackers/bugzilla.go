@@ -0,0 +1,133 @@
+package peertrackers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Fields that Bugzilla searches for the csswg-drafts link: the bug's url and
+// see also. Comments aren't searched; a regexp over every comment is too slow
+// on large instances.
+var bugzillaSearchFields = []string{"bug_file_loc", "see_also"}
+
+// A Bugzilla instance searched through its REST API, e.g.
+// bugzilla.mozilla.org or bugs.webkit.org.
+type Bugzilla struct {
+	name    string
+	baseURL string
+	client  *http.Client
+}
+
+// Creates a tracker for the Bugzilla at base_url. client may be nil to use
+// http.DefaultClient.
+func NewBugzilla(name, base_url string, client *http.Client) *Bugzilla {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Bugzilla{
+		name:    name,
+		baseURL: strings.TrimSuffix(base_url, "/"),
+		client:  client,
+	}
+}
+
+func (b *Bugzilla) Name() string {
+	return b.name
+}
+
+func (b *Bugzilla) bugURL(id int) string {
+	return fmt.Sprintf("%s/show_bug.cgi?id=%d", b.baseURL, id)
+}
+
+// Returns the query for bugs with the csswg-drafts issue url in any of
+// bugzillaSearchFields. The substring also matches issues whose number starts
+// with the same digits, so the results need to be checked with
+// bugzillaBug.mentions.
+func bugzillaSearchQuery(number int) url.Values {
+	query := url.Values{}
+	query.Set("j_top", "OR")
+	for i, field := range bugzillaSearchFields {
+		query.Set(fmt.Sprintf("f%d", i+1), field)
+		query.Set(fmt.Sprintf("o%d", i+1), "substring")
+		query.Set(fmt.Sprintf("v%d", i+1), DraftsIssueURL(number))
+	}
+	query.Set("include_fields", "id,summary,status,resolution,url,see_also")
+	return query
+}
+
+type bugzillaBug struct {
+	Id         int      `json:"id"`
+	Summary    string   `json:"summary"`
+	Status     string   `json:"status"`
+	Resolution string   `json:"resolution"`
+	URL        string   `json:"url"`
+	SeeAlso    []string `json:"see_also"`
+}
+
+// Returns whether the bug links to the csswg-drafts issue.
+func (bug *bugzillaBug) mentions(number int) bool {
+	if MentionsDraftsIssue(bug.URL, number) {
+		return true
+	}
+	for _, see_also := range bug.SeeAlso {
+		if MentionsDraftsIssue(see_also, number) {
+			return true
+		}
+	}
+	return false
+}
+
+type bugzillaResponse struct {
+	Bugs []*bugzillaBug `json:"bugs"`
+	// Set on errors
+	Error   bool   `json:"error"`
+	Message string `json:"message"`
+}
+
+func (b *Bugzilla) Search(ctx context.Context, number int) ([]*Bug, error) {
+	search_url := fmt.Sprintf("%s/rest/bug?%s", b.baseURL, bugzillaSearchQuery(number).Encode())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, search_url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequest: %v", err)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := b.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %v", b.baseURL, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ReadAll: %v", err)
+	}
+
+	var parsed bugzillaResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("search %s: %s: %v", b.baseURL, response.Status, err)
+	}
+	if parsed.Error || response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search %s: %s: %s", b.baseURL, response.Status, parsed.Message)
+	}
+
+	var bugs []*Bug
+	for _, found := range parsed.Bugs {
+		if !found.mentions(number) {
+			continue
+		}
+		bugs = append(bugs, &Bug{
+			Tracker:    b.name,
+			Id:         found.Id,
+			Summary:    found.Summary,
+			Status:     found.Status,
+			Resolution: found.Resolution,
+			URL:        b.bugURL(found.Id),
+		})
+	}
+	return bugs, nil
+}
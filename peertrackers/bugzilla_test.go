@@ -0,0 +1,50 @@
+package peertrackers
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBugzillaSearch(t *testing.T) {
+	fake := NewFakeBugzilla(
+		&FakeBugzillaBug{Id: 1, Summary: "Linked by url", Status: "NEW",
+			URL: "https://github.com/w3c/csswg-drafts/issues/123"},
+		&FakeBugzillaBug{Id: 2, Summary: "Linked by see also", Status: "RESOLVED", Resolution: "FIXED",
+			SeeAlso: []string{"https://example.com/", "https://github.com/w3c/csswg-drafts/issues/123#issuecomment-1"}},
+		&FakeBugzillaBug{Id: 3, Summary: "Longer issue number", Status: "NEW",
+			URL: "https://github.com/w3c/csswg-drafts/issues/1234"},
+		&FakeBugzillaBug{Id: 4, Summary: "Only mentioned in a comment", Status: "NEW",
+			Comments: []string{"See https://github.com/w3c/csswg-drafts/issues/123"}},
+	)
+	defer fake.Close()
+
+	bugzilla := NewBugzilla("Gecko", fake.URL, nil)
+	bugs, err := bugzilla.Search(context.Background(), 123)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(bugs) != 2 {
+		t.Fatalf("Search found %d bugs, want 2: %+v", len(bugs), bugs)
+	}
+	if bugs[0].Id != 1 || bugs[1].Id != 2 {
+		t.Errorf("Search found bugs %d and %d, want 1 and 2", bugs[0].Id, bugs[1].Id)
+	}
+	if bugs[1].Tracker != "Gecko" || bugs[1].State() != "RESOLVED FIXED" {
+		t.Errorf("bug 2 is %s %q, want Gecko \"RESOLVED FIXED\"", bugs[1].Tracker, bugs[1].State())
+	}
+	if want := fake.URL + "/show_bug.cgi?id=2"; bugs[1].URL != want {
+		t.Errorf("bug 2 url is %s, want %s", bugs[1].URL, want)
+	}
+}
+
+func TestBugzillaSearchFailure(t *testing.T) {
+	fake := NewFakeBugzilla()
+	defer fake.Close()
+	fake.SetFailure("database is down")
+
+	_, err := NewBugzilla("Gecko", fake.URL, nil).Search(context.Background(), 123)
+	if err == nil || !strings.Contains(err.Error(), "database is down") {
+		t.Errorf("Search returned %v, want the server's message", err)
+	}
+}
@@ -0,0 +1,90 @@
+package peertrackers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// One tracker to search.
+type TrackerConfig struct {
+	// Shown in the status table, e.g. "Gecko"
+	Name string `json:"name"`
+	// One of the registered types, e.g. "bugzilla"
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// The trackers to search. A json file of the form:
+//
+//	{
+//	  "trackers": [
+//	    { "name": "Gecko", "type": "bugzilla", "url": "https://bugzilla.mozilla.org" }
+//	  ]
+//	}
+type Config struct {
+	Trackers []*TrackerConfig `json:"trackers"`
+}
+
+// Used when no config file is given.
+var DefaultConfig = &Config{
+	Trackers: []*TrackerConfig{
+		{Name: "Gecko", Type: "bugzilla", URL: "https://bugzilla.mozilla.org"},
+		{Name: "WebKit", Type: "bugzilla", URL: "https://bugs.webkit.org"},
+	},
+}
+
+// Creates a tracker from its config.
+type TrackerFactory func(config *TrackerConfig, client *http.Client) Tracker
+
+var trackerTypes = map[string]TrackerFactory{
+	"bugzilla": func(config *TrackerConfig, client *http.Client) Tracker {
+		return NewBugzilla(config.Name, config.URL, client)
+	},
+}
+
+// Makes a tracker type available to config files. Call this from an init
+// function.
+func RegisterType(name string, factory TrackerFactory) {
+	trackerTypes[name] = factory
+}
+
+func LoadConfig(path string) (*Config, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return nil, fmt.Errorf("Unmarshal: %v", err)
+	}
+	for _, tracker := range config.Trackers {
+		if tracker.Name == "" || tracker.URL == "" {
+			return nil, fmt.Errorf("trackers need a name and a url")
+		}
+		if _, ok := trackerTypes[tracker.Type]; !ok {
+			return nil, fmt.Errorf("tracker %s: unknown type %q", tracker.Name, tracker.Type)
+		}
+	}
+	return &config, nil
+}
+
+// Loads the config from path, or returns DefaultConfig if path is empty.
+func LoadConfigOrDefault(path string) (*Config, error) {
+	if path == "" {
+		return DefaultConfig, nil
+	}
+	return LoadConfig(path)
+}
+
+// Creates the configured trackers. client may be nil to use
+// http.DefaultClient.
+func (c *Config) NewTrackers(client *http.Client) []Tracker {
+	var trackers []Tracker
+	for _, config := range c.Trackers {
+		trackers = append(trackers, trackerTypes[config.Type](config, client))
+	}
+	return trackers
+}
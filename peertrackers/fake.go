@@ -0,0 +1,166 @@
+package peertrackers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// A bug served by FakeBugzilla.
+type FakeBugzillaBug struct {
+	Id         int
+	Summary    string
+	Status     string
+	Resolution string
+	// Searched fields
+	URL      string
+	SeeAlso  []string
+	Comments []string
+}
+
+// An in-process Bugzilla REST server for tests. It implements the subset of
+// the search API that Bugzilla.Search uses: substring and regexp conditions on
+// the url, see also and comment fields, combined with j_top=OR or the default
+// AND.
+type FakeBugzilla struct {
+	// Base url to pass to NewBugzilla
+	URL string
+
+	server *httptest.Server
+	mutex  sync.Mutex
+	bugs   []*FakeBugzillaBug
+	// If set, searches fail with this message
+	failure string
+	// Number of searches served
+	searches int
+}
+
+func NewFakeBugzilla(bugs ...*FakeBugzillaBug) *FakeBugzilla {
+	fake := &FakeBugzilla{bugs: bugs}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.serveHTTP))
+	fake.URL = fake.server.URL
+	return fake
+}
+
+func (f *FakeBugzilla) Close() {
+	f.server.Close()
+}
+
+func (f *FakeBugzilla) AddBug(bug *FakeBugzillaBug) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.bugs = append(f.bugs, bug)
+}
+
+// Makes searches fail with message, or succeed again if message is empty.
+func (f *FakeBugzilla) SetFailure(message string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failure = message
+}
+
+func (f *FakeBugzilla) Searches() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.searches
+}
+
+func (bug *FakeBugzillaBug) fieldValues(field string) []string {
+	switch field {
+	case "bug_file_loc":
+		return []string{bug.URL}
+	case "see_also":
+		return bug.SeeAlso
+	case "longdesc":
+		return bug.Comments
+	}
+	return nil
+}
+
+type fakeCondition struct {
+	field string
+	match func(value string) bool
+}
+
+func (c *fakeCondition) matches(bug *FakeBugzillaBug) bool {
+	for _, value := range bug.fieldValues(c.field) {
+		if c.match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeFakeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": true, "message": message, "code": status,
+	})
+}
+
+func (f *FakeBugzilla) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.URL.Path != "/rest/bug" {
+		writeFakeError(w, http.StatusNotFound, fmt.Sprintf("no such resource %s", r.URL.Path))
+		return
+	}
+	f.searches++
+	if f.failure != "" {
+		writeFakeError(w, http.StatusInternalServerError, f.failure)
+		return
+	}
+
+	query := r.URL.Query()
+	var conditions []*fakeCondition
+	for i := 1; query.Get(fmt.Sprintf("f%d", i)) != ""; i++ {
+		field := query.Get(fmt.Sprintf("f%d", i))
+		operator := query.Get(fmt.Sprintf("o%d", i))
+		value := query.Get(fmt.Sprintf("v%d", i))
+		condition := &fakeCondition{field: field}
+		switch operator {
+		case "substring":
+			condition.match = func(field_value string) bool {
+				return strings.Contains(field_value, value)
+			}
+		case "regexp":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				writeFakeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			condition.match = re.MatchString
+		default:
+			writeFakeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported operator %q", operator))
+			return
+		}
+		conditions = append(conditions, condition)
+	}
+	any := strings.EqualFold(query.Get("j_top"), "OR")
+
+	bugs := []*bugzillaBug{}
+	for _, bug := range f.bugs {
+		matched := !any
+		for _, condition := range conditions {
+			if any {
+				matched = matched || condition.matches(bug)
+			} else {
+				matched = matched && condition.matches(bug)
+			}
+		}
+		if matched {
+			bugs = append(bugs, &bugzillaBug{
+				Id: bug.Id, Summary: bug.Summary, Status: bug.Status, Resolution: bug.Resolution,
+				URL: bug.URL, SeeAlso: bug.SeeAlso,
+			})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&bugzillaResponse{Bugs: bugs})
+}
@@ -0,0 +1,3 @@
+module github.com/chromium-helper/csswg-resolutions/peertrackers
+
+go 1.19
@@ -0,0 +1,115 @@
+// Package peertrackers looks up bugs that other browser engines filed for a
+// csswg-drafts issue.
+package peertrackers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A bug in a peer's tracker.
+type Bug struct {
+	// Name of the tracker, e.g. "Gecko"
+	Tracker    string
+	Id         int
+	Summary    string
+	Status     string
+	Resolution string
+	URL        string
+}
+
+// Returns whether the bug was closed as fixed.
+func (b *Bug) Fixed() bool {
+	return b.Resolution == "FIXED"
+}
+
+// Returns the status with the resolution, if any, e.g. "RESOLVED FIXED".
+func (b *Bug) State() string {
+	if b.Resolution == "" {
+		return b.Status
+	}
+	return b.Status + " " + b.Resolution
+}
+
+// A bug tracker that can be searched for bugs mentioning a url.
+type Tracker interface {
+	Name() string
+	// Returns bugs that link to the csswg-drafts issue, in the bug's url or
+	// see also.
+	Search(ctx context.Context, number int) ([]*Bug, error)
+}
+
+// URL of a csswg-drafts issue, as peers link to it.
+func DraftsIssueURL(number int) string {
+	return fmt.Sprintf("https://github.com/w3c/csswg-drafts/issues/%d", number)
+}
+
+// Matches links to the csswg-drafts issue but not to issues whose number
+// starts with the same digits. The syntax works for both Go and MySQL
+// regexps.
+func DraftsIssueRegexp(number int) string {
+	return fmt.Sprintf(`github\.com/w3c/csswg-drafts/issues/%d([^0-9]|$)`, number)
+}
+
+// Returns whether text links to the csswg-drafts issue.
+func MentionsDraftsIssue(text string, number int) bool {
+	return regexp.MustCompile(DraftsIssueRegexp(number)).MatchString(text)
+}
+
+// Searches all trackers in parallel. A tracker that fails doesn't stop the
+// others; its error is returned along with the bugs that were found.
+func SearchAll(ctx context.Context, trackers []Tracker, number int) ([]*Bug, error) {
+	found := make([][]*Bug, len(trackers))
+	errs := make([]error, len(trackers))
+	var wg sync.WaitGroup
+	for i, tracker := range trackers {
+		wg.Add(1)
+		go func(i int, tracker Tracker) {
+			defer wg.Done()
+			found[i], errs[i] = tracker.Search(ctx, number)
+		}(i, tracker)
+	}
+	wg.Wait()
+
+	var bugs []*Bug
+	var failures []string
+	for i, tracker := range trackers {
+		if errs[i] != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", tracker.Name(), errs[i]))
+			continue
+		}
+		bugs = append(bugs, found[i]...)
+	}
+	sort.SliceStable(bugs, func(i, j int) bool {
+		if bugs[i].Tracker != bugs[j].Tracker {
+			return bugs[i].Tracker < bugs[j].Tracker
+		}
+		return bugs[i].Id < bugs[j].Id
+	})
+	if len(failures) != 0 {
+		return bugs, fmt.Errorf("SearchAll: %s", strings.Join(failures, "; "))
+	}
+	return bugs, nil
+}
+
+// Escapes text for a markdown table cell.
+func escapeCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Returns a markdown table of the bugs.
+func Markdown(bugs []*Bug) string {
+	var b strings.Builder
+	b.WriteString("| Engine | Bug | Status | Summary |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, bug := range bugs {
+		fmt.Fprintf(&b, "| %s | [%d](%s) | %s | %s |\n", escapeCell(bug.Tracker),
+			bug.Id, bug.URL, escapeCell(bug.State()), escapeCell(bug.Summary))
+	}
+	return b.String()
+}
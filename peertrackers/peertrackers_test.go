@@ -0,0 +1,76 @@
+package peertrackers
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMentionsDraftsIssue(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"https://github.com/w3c/csswg-drafts/issues/123", true},
+		{"https://github.com/w3c/csswg-drafts/issues/123#issuecomment-1", true},
+		{"https://github.com/w3c/csswg-drafts/issues/1234", false},
+		{"https://github.com/w3c/csswg-drafts/issues/12", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := MentionsDraftsIssue(test.text, 123); got != test.want {
+			t.Errorf("MentionsDraftsIssue(%q, 123) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestSearchAllPartialFailure(t *testing.T) {
+	gecko := NewFakeBugzilla(&FakeBugzillaBug{Id: 7, Status: "NEW",
+		URL: "https://github.com/w3c/csswg-drafts/issues/5"})
+	defer gecko.Close()
+	webkit := NewFakeBugzilla(&FakeBugzillaBug{Id: 8, Status: "NEW",
+		URL: "https://github.com/w3c/csswg-drafts/issues/5"})
+	defer webkit.Close()
+	webkit.SetFailure("overloaded")
+
+	trackers := []Tracker{
+		NewBugzilla("WebKit", webkit.URL, nil),
+		NewBugzilla("Gecko", gecko.URL, nil),
+	}
+	bugs, err := SearchAll(context.Background(), trackers, 5)
+	if err == nil || !strings.Contains(err.Error(), "WebKit") {
+		t.Errorf("SearchAll returned %v, want the WebKit failure", err)
+	}
+	if len(bugs) != 1 || bugs[0].Tracker != "Gecko" || bugs[0].Id != 7 {
+		t.Errorf("SearchAll found %+v, want Gecko bug 7", bugs)
+	}
+	if gecko.Searches() != 1 || webkit.Searches() != 1 {
+		t.Errorf("searched Gecko %d and WebKit %d times, want once each",
+			gecko.Searches(), webkit.Searches())
+	}
+}
+
+func TestSearchAllSorted(t *testing.T) {
+	webkit := NewFakeBugzilla(
+		&FakeBugzillaBug{Id: 9, URL: "https://github.com/w3c/csswg-drafts/issues/5"},
+		&FakeBugzillaBug{Id: 3, URL: "https://github.com/w3c/csswg-drafts/issues/5"})
+	defer webkit.Close()
+	gecko := NewFakeBugzilla(&FakeBugzillaBug{Id: 20, URL: "https://github.com/w3c/csswg-drafts/issues/5"})
+	defer gecko.Close()
+
+	trackers := []Tracker{
+		NewBugzilla("WebKit", webkit.URL, nil),
+		NewBugzilla("Gecko", gecko.URL, nil),
+	}
+	bugs, err := SearchAll(context.Background(), trackers, 5)
+	if err != nil {
+		t.Fatalf("SearchAll: %v", err)
+	}
+	var got []string
+	for _, bug := range bugs {
+		got = append(got, bug.URL[strings.LastIndex(bug.URL, "=")+1:]+"@"+bug.Tracker)
+	}
+	if want := "20@Gecko 3@WebKit 9@WebKit"; strings.Join(got, " ") != want {
+		t.Errorf("SearchAll found %s, want %s", strings.Join(got, " "), want)
+	}
+}
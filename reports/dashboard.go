@@ -0,0 +1,135 @@
+package reports
+
+import (
+	"sort"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+)
+
+// Returns the time of the first stored resolution, or zero if none are stored.
+func FirstResolutionTime(data *fsresolutions.FSResolutionData) time.Time {
+	var first time.Time
+	for _, resolution := range data.Resolutions {
+		if first.IsZero() || resolution.Time.Before(first) {
+			first = resolution.Time
+		}
+	}
+	return first
+}
+
+// Returns how long triagers took to decide after the first resolution, and
+// false if the issue isn't triaged or we don't know when either happened.
+func TimeToTriage(data *fsresolutions.FSResolutionData) (time.Duration, bool) {
+	switch Outcome(data) {
+	case OutcomeUntriaged, fsresolutions.TriageOutcomeDeferred:
+		return 0, false
+	}
+	first := FirstResolutionTime(data)
+	if first.IsZero() || data.TriageDecidedTime.IsZero() ||
+		data.TriageDecidedTime.Before(first) {
+		return 0, false
+	}
+	return data.TriageDecidedTime.Sub(first), true
+}
+
+// Returns the median of durations, or zero if there are none.
+func MedianDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// Triage status of the issues with one spec label.
+type SpecSummary struct {
+	Spec string
+	// Newest resolution first
+	Datas      []*fsresolutions.FSResolutionData
+	Untriaged  int
+	Deferred   int
+	OpenCrbugs int
+	// Median TimeToTriage, over the TimedCount issues where it's known
+	MedianTimeToTriage time.Duration
+	TimedCount         int
+}
+
+// Triage status of all issues, and per spec label. An issue with several spec
+// labels counts for each of them.
+type Dashboard struct {
+	All    *SpecSummary
+	BySpec map[string]*SpecSummary
+}
+
+func newSpecSummary(spec string, datas []*fsresolutions.FSResolutionData) *SpecSummary {
+	summary := &SpecSummary{Spec: spec, Datas: datas}
+	sort.Slice(datas, func(i, j int) bool {
+		ti, tj := datas[i].LatestResolutionTime(), datas[j].LatestResolutionTime()
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return datas[i].CsswgDraftsId > datas[j].CsswgDraftsId
+	})
+
+	var durations []time.Duration
+	for _, data := range datas {
+		switch Outcome(data) {
+		case OutcomeUntriaged:
+			summary.Untriaged++
+		case fsresolutions.TriageOutcomeDeferred:
+			summary.Deferred++
+		}
+		if CrbugState(data) == CrbugOpen {
+			summary.OpenCrbugs++
+		}
+		if duration, ok := TimeToTriage(data); ok {
+			durations = append(durations, duration)
+		}
+	}
+	summary.TimedCount = len(durations)
+	summary.MedianTimeToTriage = MedianDuration(durations)
+	return summary
+}
+
+func NewDashboard(datas []*fsresolutions.FSResolutionData) *Dashboard {
+	by_spec := make(map[string][]*fsresolutions.FSResolutionData)
+	for _, data := range datas {
+		labels := data.SpecLabels
+		if len(labels) == 0 {
+			labels = []string{NoSpecLabel}
+		}
+		for _, label := range labels {
+			by_spec[label] = append(by_spec[label], data)
+		}
+	}
+
+	dashboard := &Dashboard{
+		All:    newSpecSummary("", append([]*fsresolutions.FSResolutionData(nil), datas...)),
+		BySpec: make(map[string]*SpecSummary),
+	}
+	for spec, spec_datas := range by_spec {
+		dashboard.BySpec[spec] = newSpecSummary(spec, spec_datas)
+	}
+	return dashboard
+}
+
+// Spec summaries sorted by spec label, with NoSpecLabel last.
+func (d *Dashboard) Specs() []*SpecSummary {
+	var specs []*SpecSummary
+	for spec, summary := range d.BySpec {
+		if spec != NoSpecLabel {
+			specs = append(specs, summary)
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Spec < specs[j].Spec })
+	if summary, ok := d.BySpec[NoSpecLabel]; ok {
+		specs = append(specs, summary)
+	}
+	return specs
+}
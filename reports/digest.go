@@ -0,0 +1,119 @@
+package reports
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+)
+
+// Heading for resolutions on csswg-drafts issues without a css-* label.
+const NoSpecLabel = "(no spec label)"
+
+const dateLayout = "2006-01-02"
+
+// A csswg-drafts issue and the resolutions recorded on it during the period.
+type DigestEntry struct {
+	Data        *fsresolutions.FSResolutionData
+	Resolutions []*fsresolutions.RecordedResolution
+}
+
+// Resolutions recorded in [From, To), grouped by spec label. An issue with
+// several spec labels is listed under each of them.
+type Digest struct {
+	From   time.Time
+	To     time.Time
+	Count  int
+	BySpec map[string][]*DigestEntry
+}
+
+func NewDigest(datas []*fsresolutions.FSResolutionData, from, to time.Time) *Digest {
+	digest := &Digest{
+		From:   from,
+		To:     to,
+		BySpec: make(map[string][]*DigestEntry),
+	}
+	for _, data := range datas {
+		entry := &DigestEntry{Data: data}
+		for _, resolution := range data.Resolutions {
+			if !resolution.Time.Before(from) && resolution.Time.Before(to) {
+				entry.Resolutions = append(entry.Resolutions, resolution)
+			}
+		}
+		if len(entry.Resolutions) == 0 {
+			continue
+		}
+
+		digest.Count += len(entry.Resolutions)
+		labels := data.SpecLabels
+		if len(labels) == 0 {
+			labels = []string{NoSpecLabel}
+		}
+		for _, label := range labels {
+			digest.BySpec[label] = append(digest.BySpec[label], entry)
+		}
+	}
+
+	for _, entries := range digest.BySpec {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Data.CsswgDraftsId < entries[j].Data.CsswgDraftsId
+		})
+	}
+	return digest
+}
+
+// Spec labels in the digest, sorted, with NoSpecLabel last.
+func (d *Digest) Specs() []string {
+	var specs []string
+	for spec := range d.BySpec {
+		if spec != NoSpecLabel {
+			specs = append(specs, spec)
+		}
+	}
+	sort.Strings(specs)
+	if _, ok := d.BySpec[NoSpecLabel]; ok {
+		specs = append(specs, NoSpecLabel)
+	}
+	return specs
+}
+
+func (d *Digest) Title() string {
+	// To is exclusive, but people think of the last day as included.
+	return fmt.Sprintf("CSSWG resolutions from %s to %s",
+		d.From.Format(dateLayout), d.To.AddDate(0, 0, -1).Format(dateLayout))
+}
+
+func (d *Digest) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d resolutions were recorded from %s to %s.\n",
+		d.Count, d.From.Format(dateLayout), d.To.AddDate(0, 0, -1).Format(dateLayout))
+
+	for _, spec := range d.Specs() {
+		fmt.Fprintf(&b, "\n## %s\n", spec)
+		for _, entry := range d.BySpec[spec] {
+			data := entry.Data
+			title := data.Title
+			if title == "" {
+				title = fmt.Sprintf("%s#%d", DraftsRepo, data.CsswgDraftsId)
+			}
+			fmt.Fprintf(&b, "\n### [%s](%s)\n\n", title, DraftsIssueURL(data))
+
+			status := fmt.Sprintf("Tracked in %s#%d, %s", ResolutionsRepo, data.CsswgResolutionsId, TriageState(data))
+			if crbug := CrbugMarkdown(data); crbug != "" {
+				status += ", " + crbug
+			}
+			fmt.Fprintf(&b, "%s.\n", status)
+
+			for _, resolution := range entry.Resolutions {
+				b.WriteString("\n")
+				for _, text := range resolution.Text {
+					fmt.Fprintf(&b, "> %s\n", strings.TrimSpace(text))
+				}
+				fmt.Fprintf(&b, "\n(%s)\n", resolution.CommentURL)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package reports
+
+import (
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/chromium-helper/csswg-resolutions/suggestions"
+)
+
+// Selects resolution data. Empty fields match everything.
+type Filter struct {
+	// A spec label, e.g. "css-grid-2", or a spec name without the level,
+	// e.g. "css-grid"
+	Spec string
+	// Matches if any resolution was recorded in [From, To)
+	From time.Time
+	To   time.Time
+	// See Outcome
+	Outcome string
+	// See CrbugState, or "any" for issues with a crbug
+	Crbug string
+}
+
+const CrbugAny = "any"
+
+func (f *Filter) matchesSpec(data *fsresolutions.FSResolutionData) bool {
+	for _, label := range data.SpecLabels {
+		if label == f.Spec || suggestions.SpecName(label) == f.Spec {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) matchesTime(data *fsresolutions.FSResolutionData) bool {
+	for _, resolution := range data.Resolutions {
+		if (f.From.IsZero() || !resolution.Time.Before(f.From)) &&
+			(f.To.IsZero() || resolution.Time.Before(f.To)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) Matches(data *fsresolutions.FSResolutionData) bool {
+	if f.Spec != "" && !f.matchesSpec(data) {
+		return false
+	}
+	if (!f.From.IsZero() || !f.To.IsZero()) && !f.matchesTime(data) {
+		return false
+	}
+	if f.Outcome != "" && Outcome(data) != f.Outcome {
+		return false
+	}
+	switch f.Crbug {
+	case "":
+	case CrbugAny:
+		if data.CrbugId == 0 {
+			return false
+		}
+	default:
+		if CrbugState(data) != f.Crbug {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the matching data, in the same order.
+func (f *Filter) Apply(datas []*fsresolutions.FSResolutionData) []*fsresolutions.FSResolutionData {
+	var result []*fsresolutions.FSResolutionData
+	for _, data := range datas {
+		if f.Matches(data) {
+			result = append(result, data)
+		}
+	}
+	return result
+}
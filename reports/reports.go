@@ -0,0 +1,108 @@
+// Package reports summarizes the stored resolution data for people.
+package reports
+
+import (
+	"fmt"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+)
+
+const (
+	DraftsRepo      = "w3c/csswg-drafts"
+	ResolutionsRepo = "chromium-helper/csswg-resolutions"
+)
+
+func DraftsIssueURL(data *fsresolutions.FSResolutionData) string {
+	return fmt.Sprintf("https://github.com/%s/issues/%d", DraftsRepo, data.CsswgDraftsId)
+}
+
+func TrackingIssueURL(data *fsresolutions.FSResolutionData) string {
+	return fmt.Sprintf("https://github.com/%s/issues/%d", ResolutionsRepo, data.CsswgResolutionsId)
+}
+
+// Returns the crbug url, or "" if there is no crbug.
+func CrbugURL(data *fsresolutions.FSResolutionData) string {
+	if data.CrbugId == 0 {
+		return ""
+	}
+	return fmt.Sprintf("https://crbug.com/%d", data.CrbugId)
+}
+
+// Outcome of issues that haven't been triaged yet.
+const OutcomeUntriaged = "untriaged"
+
+// Returns one of the fsresolutions.TriageOutcome constants, or
+// OutcomeUntriaged.
+func Outcome(data *fsresolutions.FSResolutionData) string {
+	if !data.DeferredUntil.IsZero() {
+		return fsresolutions.TriageOutcomeDeferred
+	}
+	if data.TriageOutcome != "" {
+		return data.TriageOutcome
+	}
+	// Data triaged before we recorded outcomes
+	if data.CrbugId != 0 {
+		return fsresolutions.TriageOutcomeCrbugFiled
+	}
+	return OutcomeUntriaged
+}
+
+// Returns a short description of how far triage got, e.g. "untriaged" or
+// "no action needed".
+func TriageState(data *fsresolutions.FSResolutionData) string {
+	switch Outcome(data) {
+	case fsresolutions.TriageOutcomeDeferred:
+		return fmt.Sprintf("deferred until %s", data.DeferredUntil.Format("2006-01-02"))
+	case fsresolutions.TriageOutcomeNoAction:
+		return "no action needed"
+	case fsresolutions.TriageOutcomeDuplicate:
+		return "duplicate"
+	case fsresolutions.TriageOutcomeCrbugFiled:
+		return "crbug filed"
+	case fsresolutions.TriageOutcomeCrbugUpdated:
+		return "crbug updated"
+	}
+	return "untriaged"
+}
+
+// Crbug statuses that count as closed.
+var closedCrbugStatuses = map[string]bool{
+	"Fixed":     true,
+	"Verified":  true,
+	"WontFix":   true,
+	"Duplicate": true,
+	"Archived":  true,
+	"Obsolete":  true,
+}
+
+// Crbug states, see CrbugState.
+const (
+	CrbugNone   = "none"
+	CrbugOpen   = "open"
+	CrbugClosed = "closed"
+)
+
+// Returns whether the issue has a crbug, and whether it's open as of the last
+// sync.
+func CrbugState(data *fsresolutions.FSResolutionData) string {
+	if data.CrbugId == 0 {
+		return CrbugNone
+	}
+	if !data.CrbugClosedTime.IsZero() || closedCrbugStatuses[data.CrbugStatus] {
+		return CrbugClosed
+	}
+	return CrbugOpen
+}
+
+// Returns a markdown link to the crbug with its status, or "" if there is no
+// crbug.
+func CrbugMarkdown(data *fsresolutions.FSResolutionData) string {
+	if data.CrbugId == 0 {
+		return ""
+	}
+	text := fmt.Sprintf("[crbug.com/%d](%s)", data.CrbugId, CrbugURL(data))
+	if data.CrbugStatus != "" {
+		text += fmt.Sprintf(" (%s)", data.CrbugStatus)
+	}
+	return text
+}
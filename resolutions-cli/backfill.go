@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/google/go-github/github"
+)
+
+const (
+	draftsOwner = "w3c"
+	draftsRepo  = "csswg-drafts"
+)
+
+// Same as parseResolutions in csswg-to-local-cf.
+var resolvedRegexp = regexp.MustCompile("(?m)^[ `*]*RESOLVED: .*$")
+
+// Data recorded before the poller stored the csswg-drafts title and labels.
+func missingIssueInfo(data *fsresolutions.FSResolutionData) bool {
+	return data.Title == ""
+}
+
+// Data with resolution comments recorded before the poller stored their text
+// and time.
+func missingResolutions(data *fsresolutions.FSResolutionData) bool {
+	return len(data.Resolutions) < len(data.ResolutionCommentIds)
+}
+
+// Data with resolutions stored before their latest time was, which the feed
+// can't find.
+func missingLatestResolvedTime(data *fsresolutions.FSResolutionData) bool {
+	return data.LatestResolvedTime.IsZero() && len(data.Resolutions) != 0
+}
+
+// Fills the title and spec labels from the csswg-drafts issue.
+func backfillIssueInfo(ctx context.Context, client *github.Client,
+	data *fsresolutions.FSResolutionData) error {
+	issue, _, err := client.Issues.Get(ctx, draftsOwner, draftsRepo, data.CsswgDraftsId)
+	if err != nil {
+		return fmt.Errorf("Issues.Get: %v", err)
+	}
+	data.Title = issue.GetTitle()
+	data.SpecLabels = nil
+	for _, label := range issue.Labels {
+		if strings.HasPrefix(label.GetName(), "css-") {
+			data.SpecLabels = append(data.SpecLabels, label.GetName())
+		}
+	}
+	return nil
+}
+
+// Fills the resolutions from the recorded comment ids, keeping the ones we
+// already have. Returns the ids of comments that no longer exist.
+func backfillResolutions(ctx context.Context, client *github.Client,
+	data *fsresolutions.FSResolutionData) ([]int64, error) {
+	recorded := make(map[int64]*fsresolutions.RecordedResolution)
+	for _, resolution := range data.Resolutions {
+		recorded[resolution.CommentId] = resolution
+	}
+
+	var resolutions []*fsresolutions.RecordedResolution
+	var missing []int64
+	for _, id := range data.ResolutionCommentIds {
+		if resolution, ok := recorded[id]; ok {
+			resolutions = append(resolutions, resolution)
+			continue
+		}
+		comment, _, err := client.Issues.GetComment(ctx, draftsOwner, draftsRepo, id)
+		var github_err *github.ErrorResponse
+		if errors.As(err, &github_err) && github_err.Response.StatusCode == http.StatusNotFound {
+			missing = append(missing, id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Issues.GetComment(%d): %v", id, err)
+		}
+		resolutions = append(resolutions, &fsresolutions.RecordedResolution{
+			CommentId:  id,
+			CommentURL: comment.GetHTMLURL(),
+			Text:       resolvedRegexp.FindAllString(comment.GetBody(), -1),
+			Time:       comment.GetCreatedAt(),
+		})
+	}
+	data.Resolutions = resolutions
+	return missing, nil
+}
+
+func runBackfill(app *App, args []string) error {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	dry_run := flags.Bool("dry-run", false, "print what would be filled without storing it")
+	flags.Parse(args)
+
+	fsclient, err := app.FSClient()
+	if err != nil {
+		return err
+	}
+	datas, err := fsclient.LoadAllData()
+	if err != nil {
+		return fmt.Errorf("LoadAllData: %v", err)
+	}
+
+	ctx := context.Background()
+	client, err := newGithubClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, data := range datas {
+		if !missingIssueInfo(data) && !missingResolutions(data) && !missingLatestResolvedTime(data) {
+			continue
+		}
+		if missingIssueInfo(data) {
+			if err := backfillIssueInfo(ctx, client, data); err != nil {
+				return fmt.Errorf("#%d: backfillIssueInfo: %v", data.CsswgDraftsId, err)
+			}
+		}
+		var missing []int64
+		if missingResolutions(data) {
+			if missing, err = backfillResolutions(ctx, client, data); err != nil {
+				return fmt.Errorf("#%d: backfillResolutions: %v", data.CsswgDraftsId, err)
+			}
+		}
+
+		var notes []string
+		for _, id := range missing {
+			notes = append(notes, fmt.Sprintf("comment %d is gone", id))
+		}
+		rows = append(rows, []string{strconv.Itoa(data.CsswgDraftsId),
+			strings.Join(data.SpecLabels, ","), strconv.Itoa(len(data.Resolutions)),
+			data.Title, strings.Join(notes, ", ")})
+		if *dry_run {
+			continue
+		}
+		err := fsclient.UpdateDataSetIssueInfo(strconv.Itoa(data.CsswgDraftsId), data)
+		if err != nil {
+			return fmt.Errorf("UpdateDataSetIssueInfo: %v", err)
+		}
+	}
+	return writeOutput(formatTable, []string{"DRAFTS", "SPECS", "RESOLUTIONS", "TITLE", "NOTES"}, rows, nil)
+}
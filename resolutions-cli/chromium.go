@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/chromiumrefs"
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/chromium-helper/csswg-resolutions/listcomments"
+	"github.com/chromium-helper/csswg-resolutions/reports"
+	"github.com/google/go-github/github"
+)
+
+// Describes the stored code for its list comment on the tracking issue.
+func chromiumRefsList(data *fsresolutions.FSResolutionData) (string, string) {
+	what := fmt.Sprintf("Chromium code that mentions [%s#%d](%s), e.g. TODOs or partial implementations",
+		reports.DraftsRepo, data.CsswgDraftsId, reports.DraftsIssueURL(data))
+	if len(data.ChromiumRefs) == 0 {
+		return what, ""
+	}
+	return what, chromiumrefs.Markdown(chromiumrefs.FromStored(data.ChromiumRefs))
+}
+
+func runChromium(app *App, args []string) error {
+	flags := flag.NewFlagSet("chromium", flag.ExitOnError)
+	checkout := flags.String("checkout", "", "chromium src checkout to scan (required)")
+	subdir := flags.String("subdir", chromiumrefs.DefaultSubdir, "part of the checkout to scan")
+	dry_run := flags.Bool("dry-run", false, "print the code found without storing or commenting")
+	flags.Parse(args)
+	if *checkout == "" {
+		flags.Usage()
+		return fmt.Errorf("-checkout is required")
+	}
+
+	fsclient, err := app.FSClient()
+	if err != nil {
+		return err
+	}
+	datas, err := fsclient.LoadAllData()
+	if err != nil {
+		return fmt.Errorf("LoadAllData: %v", err)
+	}
+	var numbers []int
+	for _, data := range datas {
+		numbers = append(numbers, data.CsswgDraftsId)
+	}
+
+	scanner := &chromiumrefs.Scanner{Dir: *checkout, Subdir: *subdir}
+	found, err := scanner.Scan(numbers)
+	if err != nil {
+		return fmt.Errorf("Scan: %v", err)
+	}
+
+	ctx := context.Background()
+	var client *github.Client
+	if !*dry_run {
+		if client, err = newGithubClient(ctx); err != nil {
+			return fmt.Errorf("newGithubClient: %v", err)
+		}
+	}
+
+	var rows [][]string
+	now := time.Now()
+	for _, data := range datas {
+		refs := found[data.CsswgDraftsId]
+		if len(refs) == 0 && len(data.ChromiumRefs) == 0 {
+			continue
+		}
+		changed := !chromiumrefs.SameRefs(refs, chromiumrefs.FromStored(data.ChromiumRefs))
+		for _, ref := range refs {
+			rows = append(rows, []string{strconv.Itoa(data.CsswgDraftsId),
+				fmt.Sprintf("%s:%d", ref.Path, ref.Line), strconv.FormatBool(changed), ref.Text})
+		}
+		if len(refs) == 0 {
+			rows = append(rows, []string{strconv.Itoa(data.CsswgDraftsId), "(none)", "true", ""})
+		}
+		if *dry_run {
+			continue
+		}
+
+		data.ChromiumRefs = chromiumrefs.ToStored(refs)
+		data.ChromiumScanTime = now
+		if changed {
+			data.ChromiumRefsChangeTime = now
+		}
+		// Line numbers in an existing comment are allowed to go stale.
+		if changed {
+			what, list := chromiumRefsList(data)
+			data.ChromiumRefsCommentId, err = listcomments.Update(ctx, client, resOwner, resRepo,
+				data.CsswgResolutionsId, data.ChromiumRefsCommentId, what, list, now)
+			if err != nil {
+				return fmt.Errorf("listcomments.Update #%d: %v", data.CsswgResolutionsId, err)
+			}
+		}
+		err := fsclient.UpdateDataSetChromiumRefs(strconv.Itoa(data.CsswgDraftsId), data)
+		if err != nil {
+			return fmt.Errorf("UpdateDataSetChromiumRefs: %v", err)
+		}
+	}
+	return writeOutput(formatTable, []string{"DRAFTS", "LOCATION", "CHANGED", "TEXT"}, rows, nil)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/reports"
+	"github.com/google/go-github/github"
+)
+
+// Where a digest can be published.
+const (
+	publishStdout = "stdout"
+	publishIssue  = "issue"
+	publishFile   = "file"
+)
+
+// Label for digest issues, so that the bot leaves them alone.
+const metaLabel = "meta"
+
+func runDigest(app *App, args []string) error {
+	yesterday := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+
+	flags := flag.NewFlagSet("digest", flag.ExitOnError)
+	last_day := flags.String("to", yesterday.Format(dateLayout), "last day of the period, YYYY-MM-DD")
+	days := flags.Int("days", 7, "number of days in the period")
+	publish := flags.String("publish", publishStdout,
+		"where to publish: stdout, issue (a new issue in csswg-resolutions) or file (a file in csswg-resolutions)")
+	path := flags.String("path", "", "path of the file in csswg-resolutions, digests/<to>.md by default")
+	flags.Parse(args)
+
+	to, err := parseDate("to", *last_day)
+	if err != nil {
+		return err
+	}
+	// The digest's To is exclusive.
+	to = to.AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -*days)
+
+	fsclient, err := app.FSClient()
+	if err != nil {
+		return err
+	}
+	datas, err := fsclient.LoadAllData()
+	if err != nil {
+		return fmt.Errorf("LoadAllData: %v", err)
+	}
+	digest := reports.NewDigest(datas, from, to)
+
+	switch *publish {
+	case publishStdout:
+		fmt.Printf("# %s\n\n%s", digest.Title(), digest.Markdown())
+		return nil
+	case publishIssue:
+		return publishDigestIssue(digest)
+	case publishFile:
+		if *path == "" {
+			*path = fmt.Sprintf("digests/%s.md", *last_day)
+		}
+		return publishDigestFile(digest, *path)
+	}
+	return fmt.Errorf("unknown -publish %q", *publish)
+}
+
+func publishDigestIssue(digest *reports.Digest) error {
+	ctx := context.Background()
+	client, err := newGithubClient(ctx)
+	if err != nil {
+		return fmt.Errorf("newGithubClient: %v", err)
+	}
+
+	title := digest.Title()
+	body := digest.Markdown()
+	labels := []string{metaLabel}
+	issue, _, err := client.Issues.Create(ctx, resOwner, resRepo,
+		&github.IssueRequest{Title: &title, Body: &body, Labels: &labels})
+	if err != nil {
+		return fmt.Errorf("Issues.Create: %v", err)
+	}
+	fmt.Printf("Filed %s\n", issue.GetHTMLURL())
+	return nil
+}
+
+// Creates or replaces the file in the csswg-resolutions repo.
+func publishDigestFile(digest *reports.Digest, path string) error {
+	ctx := context.Background()
+	client, err := newGithubClient(ctx)
+	if err != nil {
+		return fmt.Errorf("newGithubClient: %v", err)
+	}
+
+	message := fmt.Sprintf("Add digest: %s", digest.Title())
+	options := &github.RepositoryContentFileOptions{
+		Message: &message,
+		Content: []byte(fmt.Sprintf("# %s\n\n%s", digest.Title(), digest.Markdown())),
+	}
+
+	existing, _, resp, err := client.Repositories.GetContents(ctx, resOwner, resRepo, path, nil)
+	if err != nil && (resp == nil || resp.StatusCode != 404) {
+		return fmt.Errorf("Repositories.GetContents: %v", err)
+	}
+	if existing != nil {
+		options.SHA = existing.SHA
+		_, _, err = client.Repositories.UpdateFile(ctx, resOwner, resRepo, path, options)
+	} else {
+		_, _, err = client.Repositories.CreateFile(ctx, resOwner, resRepo, path, options)
+	}
+	if err != nil {
+		return fmt.Errorf("publishing %s: %v", path, err)
+	}
+	fmt.Printf("Published %s\n", path)
+	return nil
+}
@@ -0,0 +1,52 @@
+module resolutions-cli
+
+go 1.19
+
+replace github.com/chromium-helper/csswg-resolutions/fsresolutions => ../fsresolutions
+
+replace github.com/chromium-helper/csswg-resolutions/reports => ../reports
+
+require (
+	github.com/chromium-helper/csswg-resolutions/fsresolutions v0.1.0
+	github.com/chromium-helper/csswg-resolutions/reports v0.0.0-00010101000000-000000000000
+	github.com/google/go-github v17.0.0+incompatible
+	golang.org/x/oauth2 v0.5.0
+)
+
+require (
+	cloud.google.com/go v0.107.0 // indirect
+	cloud.google.com/go/compute v1.18.0 // indirect
+	cloud.google.com/go/compute/metadata v0.2.3 // indirect
+	cloud.google.com/go/firestore v1.9.0 // indirect
+	cloud.google.com/go/longrunning v0.3.0 // indirect
+	github.com/chromium-helper/csswg-resolutions/chromiumrefs v0.0.0-00010101000000-000000000000
+	github.com/chromium-helper/csswg-resolutions/listcomments v0.0.0-00010101000000-000000000000
+	github.com/chromium-helper/csswg-resolutions/suggestions v0.0.0-00010101000000-000000000000 // indirect
+	github.com/chromium-helper/csswg-resolutions/wptlinks v0.0.0-00010101000000-000000000000
+	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/google/go-cmp v0.5.9 // indirect
+	github.com/google/go-querystring v1.1.0 // indirect
+	github.com/googleapis/enterprise-certificate-proxy v0.2.3 // indirect
+	github.com/googleapis/gax-go/v2 v2.7.0 // indirect
+	go.opencensus.io v0.24.0 // indirect
+	golang.org/x/net v0.6.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/text v0.7.0 // indirect
+	golang.org/x/time v0.1.0 // indirect
+	golang.org/x/xerrors v0.0.0-20220907171357-04be3eba64a2 // indirect
+	google.golang.org/api v0.110.0 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/genproto v0.0.0-20230209215440-0dfe4f8abfcc // indirect
+	google.golang.org/grpc v1.53.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+)
+
+replace github.com/chromium-helper/csswg-resolutions/suggestions => ../suggestions
+
+replace github.com/chromium-helper/csswg-resolutions/wptlinks => ../wptlinks
+
+replace github.com/chromium-helper/csswg-resolutions/chromiumrefs => ../chromiumrefs
+
+replace github.com/chromium-helper/csswg-resolutions/listcomments => ../listcomments
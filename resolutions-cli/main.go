@@ -0,0 +1,138 @@
+// Command resolutions-cli reports on the resolution data that the bot stores
+// in firestore.
+//
+// Usage:
+//
+//	resolutions-cli [-project p] [-collection c] <command> [flags]
+//
+// Commands:
+//
+//	backfill fill data recorded before the title, labels and resolutions were stored
+//	chromium find chromium code that mentions tracked issues
+//	digest   markdown digest of the resolutions recorded in a period
+//	list     resolutions matching filters, as a table, json or csv
+//	show     everything we know about one csswg-drafts issue
+//	site     static html pages with the triage status of each spec
+//	stats    triage outcome and crbug counts per spec
+//	wpt      find web-platform-tests that reference tracked issues
+//
+// Run a command with -h for its flags. Publishing to github reads a token
+// from the GITHUB_TOKEN environment variable.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// Same as the poller in csswg-to-local-cf
+	defaultProject    = "chromium-csswg-helper"
+	defaultCollection = "resolution-db"
+
+	resOwner = "chromium-helper"
+	resRepo  = "csswg-resolutions"
+
+	dateLayout = "2006-01-02"
+)
+
+type App struct {
+	project    string
+	collection string
+	fsclient   *fsresolutions.Client
+}
+
+// Connects to firestore on first use, so that -h works without credentials.
+func (app *App) FSClient() (*fsresolutions.Client, error) {
+	if app.fsclient == nil {
+		fsclient, err := fsresolutions.NewClient(app.project, app.collection)
+		if err != nil {
+			return nil, fmt.Errorf("fsresolutions.NewClient: %v", err)
+		}
+		app.fsclient = fsclient
+	}
+	return app.fsclient, nil
+}
+
+func (app *App) Close() {
+	if app.fsclient != nil {
+		app.fsclient.Close()
+	}
+}
+
+var commands = map[string]func(app *App, args []string) error{
+	"backfill": runBackfill,
+	"chromium": runChromium,
+	"digest":   runDigest,
+	"list":     runList,
+	"show":     runShow,
+	"site":     runSite,
+	"stats":    runStats,
+	"wpt":      runWpt,
+}
+
+func newGithubClient(ctx context.Context) (*github.Client, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN is not set")
+	}
+	return github.NewClient(oauth2.NewClient(ctx,
+		oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))), nil
+}
+
+// Parses a YYYY-MM-DD flag value as a UTC date.
+func parseDate(name, value string) (time.Time, error) {
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-%s: %v", name, err)
+	}
+	return date, nil
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <command> [command flags]\n\nCommands:\n", os.Args[0])
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s\n", name)
+	}
+	fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	project := flag.String("project", defaultProject, "GCP project with the firestore database")
+	collection := flag.String("collection", defaultCollection, "firestore collection with the resolution data")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	app := &App{project: *project, collection: *collection}
+	err := command(app, flag.Args()[1:])
+	app.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
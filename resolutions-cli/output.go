@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// Writes rows as an aligned table or as csv. json_value is written instead
+// for json, so that it can keep its structure.
+func writeOutput(format string, headers []string, rows [][]string, json_value interface{}) error {
+	switch format {
+	case formatTable:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	case formatCSV:
+		w := csv.NewWriter(os.Stdout)
+		if err := w.Write(headers); err != nil {
+			return fmt.Errorf("csv: %v", err)
+		}
+		if err := w.WriteAll(rows); err != nil {
+			return fmt.Errorf("csv: %v", err)
+		}
+		return nil
+	case formatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(json_value)
+	}
+	return fmt.Errorf("unknown -format %q", format)
+}
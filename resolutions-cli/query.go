@@ -0,0 +1,303 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/chromium-helper/csswg-resolutions/reports"
+)
+
+// Adds the filter flags to flags. The returned function builds the filter
+// once the flags are parsed.
+func addFilterFlags(flags *flag.FlagSet) func() (*reports.Filter, error) {
+	spec := flags.String("spec", "", "spec label (css-grid-2) or spec name (css-grid)")
+	from := flags.String("from", "", "only resolutions recorded on or after this day, YYYY-MM-DD")
+	to := flags.String("to", "", "only resolutions recorded on or before this day, YYYY-MM-DD")
+	outcome := flags.String("outcome", "",
+		"triage outcome: untriaged, no-action, crbug-filed, crbug-updated, duplicate or deferred")
+	crbug := flags.String("crbug", "", "crbug state: none, any, open or closed")
+
+	return func() (*reports.Filter, error) {
+		filter := &reports.Filter{Spec: *spec, Outcome: *outcome, Crbug: *crbug}
+		var err error
+		if *from != "" {
+			if filter.From, err = parseDate("from", *from); err != nil {
+				return nil, err
+			}
+		}
+		if *to != "" {
+			if filter.To, err = parseDate("to", *to); err != nil {
+				return nil, err
+			}
+			// Include the whole day.
+			filter.To = filter.To.AddDate(0, 0, 1)
+		}
+		return filter, nil
+	}
+}
+
+func (app *App) loadFiltered(filter *reports.Filter) ([]*fsresolutions.FSResolutionData, error) {
+	fsclient, err := app.FSClient()
+	if err != nil {
+		return nil, err
+	}
+	datas, err := fsclient.LoadAllData()
+	if err != nil {
+		return nil, fmt.Errorf("LoadAllData: %v", err)
+	}
+	warnMissingData(filter, datas)
+	datas = filter.Apply(datas)
+	sort.Slice(datas, func(i, j int) bool {
+		return datas[i].CsswgDraftsId < datas[j].CsswgDraftsId
+	})
+	return datas, nil
+}
+
+// The spec and time filters don't match data recorded before the poller stored
+// the fields they use, so say how much of it there is.
+func warnMissingData(filter *reports.Filter, datas []*fsresolutions.FSResolutionData) {
+	var no_labels, no_resolutions int
+	for _, data := range datas {
+		if missingIssueInfo(data) {
+			no_labels++
+		}
+		if missingResolutions(data) {
+			no_resolutions++
+		}
+	}
+	if filter.Spec != "" && no_labels != 0 {
+		fmt.Fprintf(os.Stderr, "warning: -spec skips %d issues without stored spec labels, run `resolutions-cli backfill`\n", no_labels)
+	}
+	if (!filter.From.IsZero() || !filter.To.IsZero()) && no_resolutions != 0 {
+		fmt.Fprintf(os.Stderr, "warning: -from and -to skip %d issues without stored resolution times, run `resolutions-cli backfill`\n", no_resolutions)
+	}
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
+
+func formatId(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+// One row of `list`.
+type listRow struct {
+	DraftsId         int      `json:"drafts_id"`
+	TrackingId       int      `json:"tracking_id"`
+	Title            string   `json:"title,omitempty"`
+	SpecLabels       []string `json:"spec_labels,omitempty"`
+	LatestResolution string   `json:"latest_resolution,omitempty"`
+	Outcome          string   `json:"outcome"`
+	Crbug            int      `json:"crbug,omitempty"`
+	CrbugState       string   `json:"crbug_state"`
+	CrbugStatus      string   `json:"crbug_status,omitempty"`
+}
+
+func runList(app *App, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	build_filter := addFilterFlags(flags)
+	format := flags.String("format", formatTable, "output format: table, json or csv")
+	flags.Parse(args)
+
+	filter, err := build_filter()
+	if err != nil {
+		return err
+	}
+	datas, err := app.loadFiltered(filter)
+	if err != nil {
+		return err
+	}
+
+	var json_rows []*listRow
+	var rows [][]string
+	for _, data := range datas {
+		row := &listRow{
+			DraftsId:         data.CsswgDraftsId,
+			TrackingId:       data.CsswgResolutionsId,
+			Title:            data.Title,
+			SpecLabels:       data.SpecLabels,
+			LatestResolution: formatDate(data.LatestResolutionTime()),
+			Outcome:          reports.Outcome(data),
+			Crbug:            data.CrbugId,
+			CrbugState:       reports.CrbugState(data),
+			CrbugStatus:      data.CrbugStatus,
+		}
+		json_rows = append(json_rows, row)
+		rows = append(rows, []string{
+			strconv.Itoa(row.DraftsId), strconv.Itoa(row.TrackingId),
+			strings.Join(row.SpecLabels, ","), row.LatestResolution, row.Outcome,
+			formatId(row.Crbug), row.CrbugStatus, row.Title,
+		})
+	}
+	headers := []string{"DRAFTS", "TRACKING", "SPECS", "RESOLVED", "OUTCOME", "CRBUG", "STATUS", "TITLE"}
+	return writeOutput(*format, headers, rows, json_rows)
+}
+
+func runShow(app *App, args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	format := flags.String("format", formatTable, "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: show [flags] <csswg-drafts issue number>\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *format == formatCSV {
+		return fmt.Errorf("show does not support csv")
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected one csswg-drafts issue number")
+	}
+	drafts_id, err := strconv.Atoi(strings.TrimPrefix(flags.Arg(0), "#"))
+	if err != nil {
+		return fmt.Errorf("%q is not an issue number", flags.Arg(0))
+	}
+
+	fsclient, err := app.FSClient()
+	if err != nil {
+		return err
+	}
+	data, err := fsclient.LoadDataByCsswgDraftsId(drafts_id)
+	if err != nil {
+		return fmt.Errorf("LoadDataByCsswgDraftsId: %v", err)
+	}
+	if data == nil {
+		return fmt.Errorf("no data for csswg-drafts #%d", drafts_id)
+	}
+
+	rows := [][]string{
+		{"Title", data.Title},
+		{"csswg-drafts", reports.DraftsIssueURL(data)},
+		{"Tracking issue", reports.TrackingIssueURL(data)},
+		{"Spec labels", strings.Join(data.SpecLabels, ", ")},
+		{"Triage", reports.TriageState(data)},
+		{"Decided by", data.TriageDecidedBy},
+		{"Decided", formatDate(data.TriageDecidedTime)},
+		{"Reason", data.TriageReason},
+		{"Crbug", reports.CrbugURL(data)},
+		{"Crbug status", data.CrbugStatus},
+		{"Crbug owner", data.CrbugOwner},
+		{"Crbug milestone", data.CrbugMilestone},
+	}
+	for _, resolution := range data.Resolutions {
+		for _, text := range resolution.Text {
+			rows = append(rows, []string{
+				fmt.Sprintf("Resolved %s", formatDate(resolution.Time)), strings.TrimSpace(text)})
+		}
+	}
+	for _, test := range data.WptTests {
+		rows = append(rows, []string{"WPT", test})
+	}
+	for _, ref := range data.ChromiumRefs {
+		rows = append(rows, []string{"Chromium", fmt.Sprintf("%s:%d %s", ref.Path, ref.Line, ref.Text)})
+	}
+
+	// Drop empty fields, to keep the table short.
+	var non_empty [][]string
+	for _, row := range rows {
+		if row[1] != "" {
+			non_empty = append(non_empty, row)
+		}
+	}
+	return writeOutput(*format, []string{"FIELD", "VALUE"}, non_empty, data)
+}
+
+// Counts for one spec in `stats`.
+type statsRow struct {
+	Spec         string         `json:"spec"`
+	Total        int            `json:"total"`
+	Outcomes     map[string]int `json:"outcomes"`
+	OpenCrbugs   int            `json:"open_crbugs"`
+	ClosedCrbugs int            `json:"closed_crbugs"`
+}
+
+var statsOutcomes = []string{
+	reports.OutcomeUntriaged,
+	fsresolutions.TriageOutcomeNoAction,
+	fsresolutions.TriageOutcomeCrbugFiled,
+	fsresolutions.TriageOutcomeCrbugUpdated,
+	fsresolutions.TriageOutcomeDuplicate,
+	fsresolutions.TriageOutcomeDeferred,
+}
+
+func runStats(app *App, args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	build_filter := addFilterFlags(flags)
+	format := flags.String("format", formatTable, "output format: table, json or csv")
+	flags.Parse(args)
+
+	filter, err := build_filter()
+	if err != nil {
+		return err
+	}
+	datas, err := app.loadFiltered(filter)
+	if err != nil {
+		return err
+	}
+
+	by_spec := make(map[string]*statsRow)
+	count := func(spec string, data *fsresolutions.FSResolutionData) {
+		row, ok := by_spec[spec]
+		if !ok {
+			row = &statsRow{Spec: spec, Outcomes: make(map[string]int)}
+			by_spec[spec] = row
+		}
+		row.Total++
+		row.Outcomes[reports.Outcome(data)]++
+		switch reports.CrbugState(data) {
+		case reports.CrbugOpen:
+			row.OpenCrbugs++
+		case reports.CrbugClosed:
+			row.ClosedCrbugs++
+		}
+	}
+	for _, data := range datas {
+		// Spec totals overlap, since issues can have several spec labels.
+		count("(all)", data)
+		labels := data.SpecLabels
+		if len(labels) == 0 {
+			labels = []string{reports.NoSpecLabel}
+		}
+		for _, label := range labels {
+			count(label, data)
+		}
+	}
+
+	var json_rows []*statsRow
+	for _, row := range by_spec {
+		json_rows = append(json_rows, row)
+	}
+	sort.Slice(json_rows, func(i, j int) bool {
+		return json_rows[i].Spec < json_rows[j].Spec
+	})
+
+	headers := []string{"SPEC", "TOTAL"}
+	for _, outcome := range statsOutcomes {
+		headers = append(headers, strings.ToUpper(outcome))
+	}
+	headers = append(headers, "OPEN-CRBUGS", "CLOSED-CRBUGS")
+
+	var rows [][]string
+	for _, row := range json_rows {
+		cells := []string{row.Spec, strconv.Itoa(row.Total)}
+		for _, outcome := range statsOutcomes {
+			cells = append(cells, strconv.Itoa(row.Outcomes[outcome]))
+		}
+		cells = append(cells, strconv.Itoa(row.OpenCrbugs), strconv.Itoa(row.ClosedCrbugs))
+		rows = append(rows, cells)
+	}
+	return writeOutput(*format, headers, rows, json_rows)
+}
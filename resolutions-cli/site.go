@@ -0,0 +1,116 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/chromium-helper/csswg-resolutions/reports"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// Characters that don't belong in a page name.
+var pageNameRegexp = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Returns the path of a spec's page, relative to the site root.
+func specPage(spec string) string {
+	name := strings.Trim(pageNameRegexp.ReplaceAllString(strings.ToLower(spec), "-"), "-")
+	return fmt.Sprintf("specs/%s.html", name)
+}
+
+// Formats a median time to triage in days, or "-" if no issue was timed.
+func formatDuration(duration time.Duration, count int) string {
+	if count == 0 {
+		return "-"
+	}
+	days := duration.Hours() / 24
+	if days < 1 {
+		return fmt.Sprintf("%.0f hours (%d issues)", duration.Hours(), count)
+	}
+	return fmt.Sprintf("%.1f days (%d issues)", days, count)
+}
+
+func parseTemplates(generated time.Time) (*template.Template, error) {
+	funcs := template.FuncMap{
+		"generated":   func() string { return generated.Format("2006-01-02 15:04 MST") },
+		"date":        formatDate,
+		"duration":    formatDuration,
+		"specPage":    specPage,
+		"draftsURL":   reports.DraftsIssueURL,
+		"trackingURL": reports.TrackingIssueURL,
+		"crbugURL":    reports.CrbugURL,
+		"outcome":     reports.Outcome,
+		"triageState": reports.TriageState,
+		"title": func(data *fsresolutions.FSResolutionData) string {
+			if data.Title != "" {
+				return data.Title
+			}
+			return fmt.Sprintf("%s#%d", reports.DraftsRepo, data.CsswgDraftsId)
+		},
+	}
+	return template.New("site").Funcs(funcs).ParseFS(templateFiles, "templates/*.html")
+}
+
+func writePage(templates *template.Template, path, name string, value interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := templates.ExecuteTemplate(file, name, value); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return file.Close()
+}
+
+// Writes an overview page and a page per spec label to out.
+func writeSite(dashboard *reports.Dashboard, out string, generated time.Time) error {
+	templates, err := parseTemplates(generated)
+	if err != nil {
+		return fmt.Errorf("parseTemplates: %v", err)
+	}
+	if err := writePage(templates, filepath.Join(out, "index.html"), "index.html", dashboard); err != nil {
+		return err
+	}
+	for _, summary := range dashboard.Specs() {
+		path := filepath.Join(out, filepath.FromSlash(specPage(summary.Spec)))
+		if err := writePage(templates, path, "spec.html", summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runSite(app *App, args []string) error {
+	flags := flag.NewFlagSet("site", flag.ExitOnError)
+	out := flags.String("out", "site", "directory to write the html files to")
+	flags.Parse(args)
+
+	fsclient, err := app.FSClient()
+	if err != nil {
+		return err
+	}
+	datas, err := fsclient.LoadAllData()
+	if err != nil {
+		return fmt.Errorf("LoadAllData: %v", err)
+	}
+
+	dashboard := reports.NewDashboard(datas)
+	if err := writeSite(dashboard, *out, time.Now().UTC()); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d spec pages to %s\n", len(dashboard.BySpec), *out)
+	return nil
+}
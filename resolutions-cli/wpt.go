@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/wptlinks"
+)
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the paths in any of the lists, sorted and without duplicates.
+func mergePaths(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, list := range lists {
+		for _, path := range list {
+			if !seen[path] {
+				seen[path] = true
+				merged = append(merged, path)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+func runWpt(app *App, args []string) error {
+	flags := flag.NewFlagSet("wpt", flag.ExitOnError)
+	checkout := flags.String("checkout", "", "wpt checkout to scan (required)")
+	mirror := flags.Bool("mirror", false, "clone or update the checkout from -remote before scanning")
+	remote := flags.String("remote", wptlinks.DefaultRemote, "wpt git remote for -mirror")
+	days := flags.Int("days", 365, "scan commit messages from this many days back, 0 for all history")
+	dry_run := flags.Bool("dry-run", false, "print the tests found without storing them")
+	flags.Parse(args)
+	if *checkout == "" {
+		flags.Usage()
+		return fmt.Errorf("-checkout is required")
+	}
+
+	if *mirror {
+		if err := wptlinks.SyncMirror(*remote, *checkout); err != nil {
+			return fmt.Errorf("SyncMirror: %v", err)
+		}
+	}
+
+	fsclient, err := app.FSClient()
+	if err != nil {
+		return err
+	}
+	datas, err := fsclient.LoadAllData()
+	if err != nil {
+		return fmt.Errorf("LoadAllData: %v", err)
+	}
+	var numbers []int
+	for _, data := range datas {
+		numbers = append(numbers, data.CsswgDraftsId)
+	}
+
+	scanner := &wptlinks.Scanner{Dir: *checkout}
+	if *days > 0 {
+		scanner.Since = time.Now().AddDate(0, 0, -*days)
+	}
+	file_links, commit_links, err := scanner.Scan(numbers)
+	if err != nil {
+		return fmt.Errorf("Scan: %v", err)
+	}
+
+	var rows [][]string
+	now := time.Now()
+	for _, data := range datas {
+		commit_tests := commit_links[data.CsswgDraftsId]
+		if !scanner.Since.IsZero() {
+			// Older commits weren't scanned, so keep what earlier scans found
+			// in them.
+			for _, test := range data.WptCommitTests {
+				if scanner.Exists(test) {
+					commit_tests = append(commit_tests, test)
+				}
+			}
+			commit_tests = mergePaths(commit_tests)
+		}
+		tests := mergePaths(file_links[data.CsswgDraftsId], commit_tests)
+		if len(tests) == 0 && len(data.WptTests) == 0 {
+			continue
+		}
+		changed := !stringsEqual(tests, data.WptTests) ||
+			!stringsEqual(commit_tests, data.WptCommitTests)
+		rows = append(rows, []string{strconv.Itoa(data.CsswgDraftsId),
+			strconv.Itoa(len(tests)), strconv.FormatBool(changed), strings.Join(tests, " ")})
+		if *dry_run || !changed {
+			continue
+		}
+		data.WptTests = tests
+		data.WptCommitTests = commit_tests
+		data.WptScanTime = now
+		err := fsclient.UpdateDataSetWptTests(strconv.Itoa(data.CsswgDraftsId), data)
+		if err != nil {
+			return fmt.Errorf("UpdateDataSetWptTests: %v", err)
+		}
+	}
+	return writeOutput(formatTable, []string{"DRAFTS", "TESTS", "CHANGED", "PATHS"}, rows, nil)
+}
@@ -0,0 +1,122 @@
+// Package suggestions suggests triage decisions for new tracking issues, based
+// on their spec labels.
+package suggestions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Minimum number of past triage decisions before a learned component is
+// suggested.
+const kMinLearnedCount = 2
+
+// Maps spec names (e.g. "css-grid") to the components that implement them.
+type ComponentTable map[string][]string
+
+type ComponentSuggestion struct {
+	Component string
+	// Human readable explanation of where the suggestion came from.
+	Reason string
+}
+
+var specLevelRegexp = regexp.MustCompile(`-[0-9]+$`)
+
+// Strips the level from a spec label, e.g. "css-grid-2" becomes "css-grid".
+func SpecName(label string) string {
+	return specLevelRegexp.ReplaceAllString(label, "")
+}
+
+// Returns the spec names of all the css-* labels.
+func SpecNames(labels []string) []string {
+	seen := make(map[string]bool)
+	var specs []string
+	for _, label := range labels {
+		if !strings.HasPrefix(label, "css-") {
+			continue
+		}
+		spec := SpecName(label)
+		if !seen[spec] {
+			seen[spec] = true
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// Loads a json file of the form { "css-grid": ["Blink>Layout>Grid"], ... }
+func LoadComponentTable(path string) (ComponentTable, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile: %v", err)
+	}
+	return ParseComponentTable(contents)
+}
+
+// Parses the json contents of a component table file, see LoadComponentTable.
+func ParseComponentTable(contents []byte) (ComponentTable, error) {
+	var table ComponentTable
+	if err := json.Unmarshal(contents, &table); err != nil {
+		return nil, fmt.Errorf("Unmarshal: %v", err)
+	}
+	return table, nil
+}
+
+// Suggests components for the given labels. Components from the table come
+// first, followed by components that triagers picked for the same spec in the
+// past, most frequent first. stats is keyed by spec name and then component.
+func SuggestComponents(labels []string, table ComponentTable,
+	stats map[string]map[string]int) []ComponentSuggestion {
+	seen := make(map[string]bool)
+	var results []ComponentSuggestion
+
+	specs := SpecNames(labels)
+	for _, spec := range specs {
+		for _, component := range table[spec] {
+			if seen[component] {
+				continue
+			}
+			seen[component] = true
+			results = append(results, ComponentSuggestion{
+				Component: component,
+				Reason:    fmt.Sprintf("`%s` maps to it", spec),
+			})
+		}
+	}
+
+	type learned struct {
+		spec      string
+		component string
+		count     int
+	}
+	var candidates []learned
+	for _, spec := range specs {
+		for component, count := range stats[spec] {
+			if count >= kMinLearnedCount {
+				candidates = append(candidates, learned{spec, component, count})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].count != candidates[j].count {
+			return candidates[i].count > candidates[j].count
+		}
+		return candidates[i].component < candidates[j].component
+	})
+	for _, candidate := range candidates {
+		if seen[candidate.component] {
+			continue
+		}
+		seen[candidate.component] = true
+		results = append(results, ComponentSuggestion{
+			Component: candidate.component,
+			Reason: fmt.Sprintf("`%s` issues were filed in it %d times",
+				candidate.spec, candidate.count),
+		})
+	}
+	return results
+}
@@ -0,0 +1,80 @@
+package suggestions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpecNames(t *testing.T) {
+	labels := []string{"css-grid-2", "css-grid-3", "Agenda+", "css-fonts", "selectors-4"}
+	want := []string{"css-grid", "css-fonts"}
+	if got := SpecNames(labels); !reflect.DeepEqual(got, want) {
+		t.Errorf("SpecNames is %q, want %q", got, want)
+	}
+}
+
+func TestSuggestComponents(t *testing.T) {
+	table := ComponentTable{
+		"css-grid":  {"Blink>Layout>Grid"},
+		"css-fonts": {"Blink>Fonts", "Blink>Layout"},
+	}
+	stats := map[string]map[string]int{
+		"css-grid": {
+			"Blink>Layout>Grid":    5,
+			"Blink>Layout":         3,
+			"Blink>CSS":            kMinLearnedCount,
+			"Blink>Layout>Masonry": kMinLearnedCount - 1,
+		},
+		"css-fonts": {"Blink>Fonts>Loading": 3},
+	}
+
+	tests := []struct {
+		name   string
+		labels []string
+		want   []string
+	}{
+		{"table then learned", []string{"css-grid-2"},
+			[]string{"Blink>Layout>Grid", "Blink>Layout", "Blink>CSS"}},
+		// Learned components are sorted by count and then name across specs,
+		// and don't repeat the table's.
+		{"two specs", []string{"css-grid-3", "css-fonts-4"},
+			[]string{"Blink>Layout>Grid", "Blink>Fonts", "Blink>Layout", "Blink>Fonts>Loading", "Blink>CSS"}},
+		{"learned only", []string{"css-grid"}, []string{"Blink>Layout>Grid", "Blink>Layout", "Blink>CSS"}},
+		{"unknown spec", []string{"css-print"}, nil},
+		{"no spec labels", []string{"Agenda+"}, nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, suggestion := range SuggestComponents(test.labels, table, stats) {
+			got = append(got, suggestion.Component)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: SuggestComponents is %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSuggestComponentsReasons(t *testing.T) {
+	table := ComponentTable{"css-grid": {"Blink>Layout>Grid"}}
+	stats := map[string]map[string]int{"css-grid": {"Blink>Layout": 4}}
+	want := []ComponentSuggestion{
+		{"Blink>Layout>Grid", "`css-grid` maps to it"},
+		{"Blink>Layout", "`css-grid` issues were filed in it 4 times"},
+	}
+	if got := SuggestComponents([]string{"css-grid-1"}, table, stats); !reflect.DeepEqual(got, want) {
+		t.Errorf("SuggestComponents is %+v, want %+v", got, want)
+	}
+}
+
+func TestParseComponentTable(t *testing.T) {
+	table, err := ParseComponentTable([]byte(`{"css-grid": ["Blink>Layout>Grid"]}`))
+	if err != nil {
+		t.Fatalf("ParseComponentTable: %v", err)
+	}
+	if want := (ComponentTable{"css-grid": {"Blink>Layout>Grid"}}); !reflect.DeepEqual(table, want) {
+		t.Errorf("ParseComponentTable is %v, want %v", table, want)
+	}
+	if _, err := ParseComponentTable([]byte(`["css-grid"]`)); err == nil {
+		t.Errorf("ParseComponentTable of a list succeeded")
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/chromium-helper/csswg-resolutions/suggestions
+
+go 1.19
@@ -0,0 +1,49 @@
+package suggestions
+
+import (
+	"sort"
+)
+
+type PersonSuggestion struct {
+	Email string
+	// Number of past crbugs for the same specs that this person was picked for.
+	Count int
+}
+
+// Suggests up to max people for the given labels, most frequently picked
+// first. stats is keyed by spec name and then email. People in exclude are
+// never suggested.
+func SuggestPeople(labels []string, stats map[string]map[string]int,
+	max int, exclude ...string) []PersonSuggestion {
+	excluded := make(map[string]bool)
+	for _, email := range exclude {
+		excluded[email] = true
+	}
+
+	counts := make(map[string]int)
+	for _, spec := range SpecNames(labels) {
+		for email, count := range stats[spec] {
+			if !excluded[email] {
+				counts[email] += count
+			}
+		}
+	}
+
+	var results []PersonSuggestion
+	for email, count := range counts {
+		if count >= kMinLearnedCount {
+			results = append(results, PersonSuggestion{Email: email, Count: count})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Count != results[j].Count {
+			return results[i].Count > results[j].Count
+		}
+		return results[i].Email < results[j].Email
+	})
+
+	if len(results) > max {
+		results = results[:max]
+	}
+	return results
+}
@@ -0,0 +1,48 @@
+package suggestions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggestPeople(t *testing.T) {
+	stats := map[string]map[string]int{
+		"css-grid": {
+			"a@chromium.org": 4,
+			"b@chromium.org": 2,
+			"c@chromium.org": kMinLearnedCount - 1,
+		},
+		"css-align": {
+			"c@chromium.org": 1,
+			"d@chromium.org": 2,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		labels  []string
+		max     int
+		exclude []string
+		want    []PersonSuggestion
+	}{
+		{"one spec", []string{"css-grid-2"}, 5, nil,
+			[]PersonSuggestion{{"a@chromium.org", 4}, {"b@chromium.org", 2}}},
+		// Counts add up across specs, so c reaches the threshold.
+		{"two specs", []string{"css-grid-2", "css-align-3"}, 5, nil,
+			[]PersonSuggestion{{"a@chromium.org", 4}, {"b@chromium.org", 2},
+				{"c@chromium.org", 2}, {"d@chromium.org", 2}}},
+		{"max", []string{"css-grid-2", "css-align-3"}, 2, nil,
+			[]PersonSuggestion{{"a@chromium.org", 4}, {"b@chromium.org", 2}}},
+		{"excluded", []string{"css-grid-2"}, 5, []string{"a@chromium.org"},
+			[]PersonSuggestion{{"b@chromium.org", 2}}},
+		{"below the threshold", []string{"css-align"}, 5, nil,
+			[]PersonSuggestion{{"d@chromium.org", 2}}},
+		{"unknown spec", []string{"css-print"}, 5, nil, nil},
+	}
+	for _, test := range tests {
+		got := SuggestPeople(test.labels, stats, test.max, test.exclude...)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: SuggestPeople is %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ replace github.com/chromium-helper/csswg-resolutions/monorail => ../monorail
 
 require (
 	github.com/chromium-helper/csswg-resolutions/monorail v0.0.0-00010101000000-000000000000
+	golang.org/x/oauth2 v0.6.0
 	google.golang.org/api v0.114.0
 )
 
@@ -17,7 +18,6 @@ require (
 	github.com/googleapis/enterprise-certificate-proxy v0.2.3 // indirect
 	go.opencensus.io v0.24.0 // indirect
 	golang.org/x/net v0.8.0 // indirect
-	golang.org/x/oauth2 v0.6.0 // indirect
 	golang.org/x/sys v0.6.0 // indirect
 	golang.org/x/text v0.8.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/chromium-helper/csswg-resolutions/monorail"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/idtoken"
 	"context"
 	//"fmt"
@@ -14,12 +15,11 @@ func main() {
 	}
 
   ctx := context.Background()
-  token_source, err := idtoken.NewTokenSource(ctx, audience)
-  if err != nil {
-    panic(err)
+  new_token_source := func() (oauth2.TokenSource, error) {
+    return idtoken.NewTokenSource(ctx, audience)
   }
 
-  service, err := monorail.NewIssuesService(ctx, "prod", token_source)
+  service, err := monorail.NewIssuesService(ctx, "prod", new_token_source)
   if err != nil {
     panic(err)
   }
@@ -0,0 +1,83 @@
+package triagers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A set of people, given as github logins and "org/team-slug" teams.
+type Group struct {
+	Logins []string `json:"logins"`
+	Teams  []string `json:"teams"`
+}
+
+// Who may triage. A json file of the form:
+//
+//	{
+//	  "collaborators": true,
+//	  "logins": ["some-triager"],
+//	  "teams": ["w3c/chromium-css"],
+//	  "specs": { "css-grid": { "logins": ["grid-owner"] } },
+//	  "directives": { "owner": { "teams": ["w3c/chromium-css-leads"] } }
+//	}
+//
+// Collaborators with write access, the listed logins and the members of the
+// listed teams may triage any issue. Spec triagers may only triage issues
+// with a matching css-* label. A directive listed in "directives" may only be
+// used by its group, on top of being a triager.
+type Config struct {
+	Collaborators bool `json:"collaborators"`
+	Group
+	Specs      map[string]*Group `json:"specs"`
+	Directives map[string]*Group `json:"directives"`
+}
+
+// Used when no config file is given: collaborators with write access triage.
+var DefaultConfig = &Config{Collaborators: true}
+
+func LoadConfig(path string) (*Config, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return nil, fmt.Errorf("Unmarshal: %v", err)
+	}
+
+	groups := []*Group{&config.Group}
+	for _, group := range config.Specs {
+		groups = append(groups, group)
+	}
+	for _, group := range config.Directives {
+		groups = append(groups, group)
+	}
+	for _, group := range groups {
+		for _, team := range group.Teams {
+			if _, _, err := splitTeam(team); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &config, nil
+}
+
+// Loads the config from path, or returns DefaultConfig if path is empty.
+func LoadConfigOrDefault(path string) (*Config, error) {
+	if path == "" {
+		return DefaultConfig, nil
+	}
+	return LoadConfig(path)
+}
+
+// Splits "org/team-slug" into its parts.
+func splitTeam(team string) (string, string, error) {
+	parts := strings.Split(team, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("team %q is not of the form org/team-slug", team)
+	}
+	return parts[0], parts[1], nil
+}
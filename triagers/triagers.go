@@ -0,0 +1,202 @@
+// Package triagers decides whose triage actions the bot acts on.
+package triagers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromium-helper/csswg-resolutions/fsresolutions"
+	"github.com/chromium-helper/csswg-resolutions/suggestions"
+	"github.com/google/go-github/github"
+)
+
+// How long a cached permission is trusted, unless configured otherwise.
+const DefaultCacheTTL = 1 * time.Hour
+
+// Checks whether github users may triage, according to Config.
+// Collaborator permissions are cached in the store for TTL, and in memory for
+// the lifetime of the Checker. Team members are only cached in memory.
+type Checker struct {
+	GithubClient *github.Client
+	FSClient     *fsresolutions.Client
+	Owner        string
+	Repo         string
+	TTL          time.Duration
+	Config       *Config
+
+	permissions map[string]string
+	teamMembers map[string]map[string]bool
+}
+
+// If config is nil, DefaultConfig is used.
+func NewChecker(github_client *github.Client, fsclient *fsresolutions.Client,
+	owner, repo string, ttl time.Duration, config *Config) *Checker {
+	if config == nil {
+		config = DefaultConfig
+	}
+	return &Checker{
+		GithubClient: github_client,
+		FSClient:     fsclient,
+		Owner:        owner,
+		Repo:         repo,
+		TTL:          ttl,
+		Config:       config,
+		permissions:  make(map[string]string),
+		teamMembers:  make(map[string]map[string]bool),
+	}
+}
+
+// Returns the user's permission on the repo: "admin", "write", "read" or
+// "none".
+func (c *Checker) Permission(login string) (string, error) {
+	if permission, ok := c.permissions[login]; ok {
+		return permission, nil
+	}
+
+	cached, err := c.FSClient.LoadCollaboratorPermission(login)
+	if err != nil {
+		return "", fmt.Errorf("LoadCollaboratorPermission: %v", err)
+	}
+	if cached != nil && time.Since(cached.FetchTime) < c.TTL {
+		c.permissions[login] = cached.Permission
+		return cached.Permission, nil
+	}
+
+	level, _, err := c.GithubClient.Repositories.GetPermissionLevel(
+		context.Background(), c.Owner, c.Repo, login)
+	if err != nil {
+		return "", fmt.Errorf("GetPermissionLevel: %v", err)
+	}
+
+	permission := level.GetPermission()
+	err = c.FSClient.SetCollaboratorPermission(login, &fsresolutions.CollaboratorPermission{
+		Permission: permission,
+		FetchTime:  time.Now(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("SetCollaboratorPermission: %v", err)
+	}
+	c.permissions[login] = permission
+	return permission, nil
+}
+
+// Returns the logins of all members of an "org/team-slug" team.
+func (c *Checker) TeamMembers(team string) (map[string]bool, error) {
+	if members, ok := c.teamMembers[team]; ok {
+		return members, nil
+	}
+
+	org, slug, err := splitTeam(team)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var team_id int64
+	opts := &github.ListOptions{PerPage: 100}
+	for team_id == 0 {
+		teams, resp, err := c.GithubClient.Teams.ListTeams(ctx, org, opts)
+		if err != nil {
+			return nil, fmt.Errorf("ListTeams: %v", err)
+		}
+		for _, t := range teams {
+			if t.GetSlug() == slug {
+				team_id = t.GetID()
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if team_id == 0 {
+		return nil, fmt.Errorf("team %s not found", team)
+	}
+
+	members := make(map[string]bool)
+	member_opts := &github.TeamListTeamMembersOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		users, resp, err := c.GithubClient.Teams.ListTeamMembers(ctx, team_id, member_opts)
+		if err != nil {
+			return nil, fmt.Errorf("ListTeamMembers: %v", err)
+		}
+		for _, user := range users {
+			members[user.GetLogin()] = true
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		member_opts.Page = resp.NextPage
+	}
+	c.teamMembers[team] = members
+	return members, nil
+}
+
+// Returns true if the user is listed in the group, directly or via a team.
+func (c *Checker) InGroup(login string, group *Group) (bool, error) {
+	if group == nil {
+		return false, nil
+	}
+	for _, l := range group.Logins {
+		if l == login {
+			return true, nil
+		}
+	}
+	for _, team := range group.Teams {
+		members, err := c.TeamMembers(team)
+		if err != nil {
+			return false, fmt.Errorf("TeamMembers: %v", err)
+		}
+		if members[login] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns true if the user may triage any issue.
+func (c *Checker) IsTriager(login string) (bool, error) {
+	if c.Config.Collaborators {
+		permission, err := c.Permission(login)
+		if err != nil {
+			return false, err
+		}
+		if permission == "admin" || permission == "write" {
+			return true, nil
+		}
+	}
+	return c.InGroup(login, &c.Config.Group)
+}
+
+// Returns true if the user may triage an issue with the given labels, either
+// as a general triager or as a triager for one of its specs.
+func (c *Checker) IsTriagerFor(login string, labels []string) (bool, error) {
+	is_triager, err := c.IsTriager(login)
+	if err != nil || is_triager {
+		return is_triager, err
+	}
+	for _, spec := range suggestions.SpecNames(labels) {
+		in_group, err := c.InGroup(login, c.Config.Specs[spec])
+		if err != nil || in_group {
+			return in_group, err
+		}
+	}
+	return false, nil
+}
+
+// Returns true if the user may use the directive (e.g. "owner") on an issue
+// with the given labels.
+func (c *Checker) MayUseDirective(login string, directive string, labels []string) (bool, error) {
+	is_triager, err := c.IsTriagerFor(login, labels)
+	if err != nil || !is_triager {
+		return false, err
+	}
+	group, restricted := c.Config.Directives[directive]
+	if !restricted {
+		return true, nil
+	}
+	return c.InGroup(login, group)
+}
@@ -0,0 +1,3 @@
+module github.com/chromium-helper/csswg-resolutions/wptlinks
+
+go 1.19
@@ -0,0 +1,233 @@
+// Package wptlinks finds web-platform-tests that reference csswg-drafts
+// issues, in a local checkout of the wpt repo.
+package wptlinks
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Upstream wpt, for mirrors.
+const DefaultRemote = "https://github.com/web-platform-tests/wpt.git"
+
+// Matches csswg-drafts issue references, as urls or as w3c/csswg-drafts#N.
+var issueRegexp = regexp.MustCompile(`csswg-drafts(?:/issues/|/pull/|#)(\d+)`)
+
+// Top level directories of the wpt repo that don't hold tests.
+var nonTestDirs = map[string]bool{
+	"docs":         true,
+	"resources":    true,
+	"tools":        true,
+	"node_modules": true,
+}
+
+// Files larger than this aren't scanned; they are generated or binary.
+const maxFileSize = 1 << 20
+
+// Scans a wpt checkout.
+type Scanner struct {
+	// Root of the checkout
+	Dir string
+	// Only commit messages since this time are scanned. Zero scans the whole
+	// history, which is slow.
+	Since time.Time
+}
+
+// Returns whether the slash separated path, relative to the checkout, can be
+// a test.
+func isTestPath(path string) bool {
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || nonTestDirs[parts[0]] {
+		return false
+	}
+	for _, part := range parts {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the issue numbers referenced in text that are in numbers.
+func findIssues(text []byte, numbers map[int]bool) []int {
+	var found []int
+	for _, match := range issueRegexp.FindAllSubmatch(text, -1) {
+		number, err := strconv.Atoi(string(match[1]))
+		if err == nil && numbers[number] {
+			found = append(found, number)
+		}
+	}
+	return found
+}
+
+// Maps issue numbers to the test paths that reference them.
+type Links map[int][]string
+
+func (l Links) add(number int, path string) {
+	for _, existing := range l[number] {
+		if existing == path {
+			return
+		}
+	}
+	l[number] = append(l[number], path)
+}
+
+// Returns the tests that reference any of the csswg-drafts issue numbers in
+// their contents, and the ones that changed in a commit whose message does.
+// Paths are relative to the checkout and sorted.
+func (s *Scanner) Scan(numbers []int) (files Links, commits Links, err error) {
+	wanted := make(map[int]bool)
+	for _, number := range numbers {
+		wanted[number] = true
+	}
+
+	files = make(Links)
+	if err := s.scanFiles(wanted, files); err != nil {
+		return nil, nil, fmt.Errorf("scanFiles: %v", err)
+	}
+	commits = make(Links)
+	if err := s.scanCommits(wanted, commits); err != nil {
+		return nil, nil, fmt.Errorf("scanCommits: %v", err)
+	}
+	for _, links := range []Links{files, commits} {
+		for _, paths := range links {
+			sort.Strings(paths)
+		}
+	}
+	return files, commits, nil
+}
+
+// Returns whether the slash separated path, relative to the checkout, exists.
+// Tests may have been moved or removed since a commit changed them.
+func (s *Scanner) Exists(path string) bool {
+	_, err := os.Stat(filepath.Join(s.Dir, filepath.FromSlash(path)))
+	return err == nil
+}
+
+func (s *Scanner) scanFiles(numbers map[int]bool, links Links) error {
+	return filepath.WalkDir(s.Dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		relative = filepath.ToSlash(relative)
+		if entry.IsDir() {
+			if relative != "." && (strings.HasPrefix(entry.Name(), ".") ||
+				(!strings.Contains(relative, "/") && nonTestDirs[relative])) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !isTestPath(relative) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// Skip binary files
+		if bytes.IndexByte(contents[:min(len(contents), 512)], 0) != -1 {
+			return nil
+		}
+		for _, number := range findIssues(contents, numbers) {
+			links.add(number, relative)
+		}
+		return nil
+	})
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Separators in the git log output
+const (
+	commitSeparator = "\x1e"
+	filesSeparator  = "\x1f"
+)
+
+func (s *Scanner) scanCommits(numbers map[int]bool, links Links) error {
+	// -z ends each path with a NUL instead of quoting unusual ones.
+	args := []string{"-C", s.Dir, "log", "--no-merges", "--name-only", "-z",
+		"--format=" + commitSeparator + "%B" + filesSeparator}
+	if !s.Since.IsZero() {
+		args = append(args, "--since="+s.Since.Format(time.RFC3339))
+	}
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return fmt.Errorf("git log: %v", err)
+	}
+
+	for _, commit := range strings.Split(string(output), commitSeparator) {
+		parts := strings.SplitN(commit, filesSeparator, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		referenced := findIssues([]byte(parts[0]), numbers)
+		if len(referenced) == 0 {
+			continue
+		}
+		for _, path := range strings.Split(parts[1], "\x00") {
+			// The first path follows the newline after the message.
+			path = strings.TrimPrefix(path, "\n")
+			if path == "" || !isTestPath(path) {
+				continue
+			}
+			if !s.Exists(path) {
+				continue
+			}
+			for _, number := range referenced {
+				links.add(number, path)
+			}
+		}
+	}
+	return nil
+}
+
+// Clones remote into dir, or updates dir if it's already a clone, so that it
+// can be scanned. Local changes in dir are discarded.
+func SyncMirror(remote, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		output, err := exec.Command("git", "clone", "--quiet", remote, dir).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("git clone: %v: %s", err, output)
+		}
+		return nil
+	}
+
+	for _, args := range [][]string{
+		{"fetch", "--quiet", remote},
+		{"reset", "--hard", "--quiet", "FETCH_HEAD"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("git %s: %v: %s", args[0], err, output)
+		}
+	}
+	return nil
+}
+
+// Returns the wpt.fyi url for a test path.
+func ResultsURL(path string) string {
+	return "https://wpt.fyi/results/" + path
+}
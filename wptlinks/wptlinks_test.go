@@ -0,0 +1,126 @@
+package wptlinks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestIsTestPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"css/css-grid/grid-001.html", true},
+		{"css/css-grid/support/image.png", true},
+		{"README.md", false},
+		{"resources/testharness.js", false},
+		{"tools/wpt/run.py", false},
+		{"docs/writing-tests/index.md", false},
+		{".github/workflows/ci.yml", false},
+		{"css/.hidden/test.html", false},
+		{"css/resources/helper.js", true},
+	}
+	for _, test := range tests {
+		if got := isTestPath(test.path); got != test.want {
+			t.Errorf("isTestPath(%q) is %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestFindIssues(t *testing.T) {
+	numbers := map[int]bool{100: true, 200: true, 300: true}
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"https://github.com/w3c/csswg-drafts/issues/100", []int{100}},
+		{"See w3c/csswg-drafts#200 and w3c/csswg-drafts/pull/300.", []int{200, 300}},
+		{"w3c/csswg-drafts#999 isn't tracked", nil},
+		{"w3c/fxtf-drafts#100 is another repo", nil},
+		{"https://github.com/w3c/csswg-drafts/issues/1000", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if got := findIssues([]byte(test.text), numbers); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("findIssues(%q) is %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+// Runs git in dir, failing the test if it fails.
+func runGit(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+// Writes the files and commits them with the message.
+func commitFiles(t *testing.T, dir, message string, files ...string) {
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(message), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		runGit(t, dir, "add", "--", file)
+	}
+	runGit(t, dir, "commit", "-q", "-m", message)
+}
+
+func TestScanCommits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+
+	// git log quotes paths like these without -z.
+	commitFiles(t, dir, "Test w3c/csswg-drafts#100\n\nMore details.",
+		"css/a b.html", "css/tab\there.html", "css/ünïcode.html", "css/quote\"d.html",
+		"tools/helper.py")
+	commitFiles(t, dir, "Fix https://github.com/w3c/csswg-drafts/issues/200",
+		"css/removed.html", "css/multi-1.html")
+	commitFiles(t, dir, "Unrelated\n\nw3c/csswg-drafts#999", "css/other.html")
+	runGit(t, dir, "rm", "-q", "css/removed.html")
+	runGit(t, dir, "commit", "-q", "-m", "Remove w3c/csswg-drafts#200 test")
+
+	scanner := &Scanner{Dir: dir}
+	links := make(Links)
+	if err := scanner.scanCommits(map[int]bool{100: true, 200: true}, links); err != nil {
+		t.Fatalf("scanCommits: %v", err)
+	}
+	want := Links{
+		100: {"css/a b.html", "css/quote\"d.html", "css/tab\there.html", "css/ünïcode.html"},
+		200: {"css/multi-1.html"},
+	}
+	for number, paths := range want {
+		sort.Strings(links[number])
+		if !reflect.DeepEqual(links[number], paths) {
+			t.Errorf("tests for #%d are %q, want %q", number, links[number], paths)
+		}
+	}
+	if len(links) != len(want) {
+		t.Errorf("links are %q, want %q", links, want)
+	}
+
+	// Commits before Since aren't scanned.
+	scanner.Since = time.Now().Add(time.Hour)
+	links = make(Links)
+	if err := scanner.scanCommits(map[int]bool{100: true, 200: true}, links); err != nil {
+		t.Fatalf("scanCommits: %v", err)
+	}
+	if len(links) != 0 {
+		t.Errorf("links since an hour from now are %q", links)
+	}
+}